@@ -0,0 +1,243 @@
+package hyperloglog
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+var (
+	accuracyTrials = flag.Int("accuracy.trials", 40, "number of trials per point in TestAccuracy")
+	accuracyOut    = flag.String("accuracy.out", "", "directory TestAccuracy writes CSV data and SVG plots to")
+)
+
+var accuracyAlgorithms = []struct {
+	name string
+	new  func(p uint8) (add func([]uint64), count func() uint64)
+}{
+	{"HyperLogLog", func(p uint8) (func([]uint64), func() uint64) {
+		h, _ := New(p)
+		return h.AddHashes64, h.Count
+	}},
+	{"HyperLogLog64", func(p uint8) (func([]uint64), func() uint64) {
+		h, _ := New64(p)
+		return h.AddHashes64, h.Count
+	}},
+	{"HyperLogLog++", func(p uint8) (func([]uint64), func() uint64) {
+		h, _ := NewPlus(p)
+		return h.AddHashes, h.Count
+	}},
+}
+
+// The relative errors of one algorithm at one precision and cardinality.
+type accuracyPoint struct {
+	algorithm string
+	p         uint8
+	n         int
+	mean, std float64
+}
+
+// Measures the mean and standard deviation of the relative error of each
+// algorithm at precision p and each of cardinalities, which must be
+// increasing. Each trial adds a new seeded stream of random hashes.
+func measureAccuracy(p uint8, cardinalities []int, trials int) []accuracyPoint {
+	var points []accuracyPoint
+	for a, alg := range accuracyAlgorithms {
+		errs := make([][]float64, len(cardinalities))
+		for t := 0; t < trials; t++ {
+			r := rand.New(rand.NewSource(int64(p)<<32 | int64(a)<<16 | int64(t)))
+			add, count := alg.new(p)
+
+			var xs []uint64
+			added := 0
+			for i, n := range cardinalities {
+				xs = xs[:0]
+				for ; added < n; added++ {
+					xs = append(xs, r.Uint64())
+				}
+				add(xs)
+				errs[i] = append(errs[i], (float64(count())-float64(n))/float64(n))
+			}
+		}
+
+		for i, n := range cardinalities {
+			mean, std := meanStd(errs[i])
+			points = append(points, accuracyPoint{alg.name, p, n, mean, std})
+		}
+	}
+	return points
+}
+
+func meanStd(xs []float64) (float64, float64) {
+	var sum, sq float64
+	for _, x := range xs {
+		sum += x
+	}
+	mean := sum / float64(len(xs))
+	for _, x := range xs {
+		sq += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(xs)-1))
+}
+
+// Returns the cardinalities TestAccuracy measures at precision p. Plots get
+// evenly spaced points up to 5m, where the estimators differ the most.
+func accuracyCardinalities(p uint8, plot bool) []int {
+	m := 1 << p
+	var cardinalities []int
+	if plot {
+		for i := 1; i <= 100; i++ {
+			cardinalities = append(cardinalities, i*5*m/100)
+		}
+		return cardinalities
+	}
+
+	for _, f := range []float64{0.1, 0.5, 1, 2, 2.5, 3, 4, 5, 10} {
+		cardinalities = append(cardinalities, int(f*float64(m)))
+	}
+	return cardinalities
+}
+
+func TestAccuracy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping accuracy test in short mode")
+	}
+
+	var all []accuracyPoint
+	for _, p := range []uint8{6, 10, 14} {
+		points := measureAccuracy(p, accuracyCardinalities(p, *accuracyOut != ""), *accuracyTrials)
+		all = append(all, points...)
+
+		// The standard error of HyperLogLog is 1.04/sqrt(m). The bounds allow
+		// for the sampling error of the measured mean and deviation, about
+		// std/sqrt(trials) and std/sqrt(2(trials-1)).
+		m := 1 << p
+		sigma := 1.04 / math.Sqrt(float64(m))
+		trials := float64(*accuracyTrials)
+		for _, pt := range points {
+			bias := 0.5*sigma + 3*pt.std/math.Sqrt(trials)
+			std := 1.2*sigma + 2*pt.std/math.Sqrt(2*(trials-1))
+
+			// The original algorithm is known to be biased from 2.5m, where it
+			// stops using linear counting, until the raw estimate catches up.
+			// HyperLogLog++ corrects for this.
+			if pt.algorithm != "HyperLogLog++" && pt.n >= 5*m/2 && pt.n < 4*m {
+				bias = 4 * sigma
+			}
+
+			if math.Abs(pt.mean) > bias {
+				t.Errorf("%s p=%d n=%d: mean relative error %f exceeds %f", pt.algorithm, p, pt.n, pt.mean, bias)
+			}
+			if pt.std > std {
+				t.Errorf("%s p=%d n=%d: relative error deviation %f exceeds %f", pt.algorithm, p, pt.n, pt.std, std)
+			}
+		}
+
+		if *accuracyOut != "" {
+			name := filepath.Join(*accuracyOut, fmt.Sprintf("accuracy_p%d.svg", p))
+			if err := writeAccuracyFile(name, func(w io.Writer) error {
+				return writeAccuracySVG(w, p, points)
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if *accuracyOut != "" {
+		name := filepath.Join(*accuracyOut, "accuracy.csv")
+		if err := writeAccuracyFile(name, func(w io.Writer) error {
+			return writeAccuracyCSV(w, all)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeAccuracyFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeAccuracyCSV(w io.Writer, points []accuracyPoint) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"algorithm", "precision", "cardinality", "mean_error", "std_error"})
+	for _, pt := range points {
+		cw.Write([]string{
+			pt.algorithm,
+			strconv.Itoa(int(pt.p)),
+			strconv.Itoa(pt.n),
+			strconv.FormatFloat(pt.mean, 'g', -1, 64),
+			strconv.FormatFloat(pt.std, 'g', -1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Plots the mean relative error of each algorithm against cardinality, with
+// dashed lines at one standard error.
+func writeAccuracySVG(w io.Writer, p uint8, points []accuracyPoint) error {
+	const width, height, margin = 640, 400, 60
+	colors := []string{"#d62728", "#ff7f0e", "#1f77b4"}
+
+	sigma := 1.04 / math.Sqrt(float64(int(1)<<p))
+	maxN, maxErr := 1, 1.5*sigma
+	for _, pt := range points {
+		if pt.n > maxN {
+			maxN = pt.n
+		}
+		maxErr = math.Max(maxErr, math.Abs(pt.mean)*1.1)
+	}
+	x := func(n int) float64 {
+		return margin + float64(n)/float64(maxN)*(width-2*margin)
+	}
+	y := func(e float64) float64 {
+		return height/2 - e/maxErr*(height/2-margin)
+	}
+
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(w, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle" font-size="14">Mean relative error, p=%d, %d trials</text>`+"\n",
+		width/2, margin/2, p, *accuracyTrials)
+	fmt.Fprintf(w, `<line x1="%d" y1="%g" x2="%d" y2="%g" stroke="black"/>`+"\n", margin, y(0), width-margin, y(0))
+	fmt.Fprintf(w, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", margin, margin, margin, height-margin)
+	for _, e := range []float64{-sigma, sigma} {
+		fmt.Fprintf(w, `<line x1="%d" y1="%g" x2="%d" y2="%g" stroke="gray" stroke-dasharray="4"/>`+"\n",
+			margin, y(e), width-margin, y(e))
+		fmt.Fprintf(w, `<text x="%d" y="%g" text-anchor="end">%.2f%%</text>`+"\n", margin-4, y(e)+4, e*100)
+	}
+	for i := 0; i <= 4; i++ {
+		n := maxN * i / 4
+		fmt.Fprintf(w, `<text x="%g" y="%d" text-anchor="middle">%d</text>`+"\n", x(n), height-margin+16, n)
+	}
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle">cardinality</text>`+"\n", width/2, height-margin/3)
+
+	for i, alg := range accuracyAlgorithms {
+		fmt.Fprintf(w, `<polyline fill="none" stroke="%s" points="`, colors[i%len(colors)])
+		for _, pt := range points {
+			if pt.algorithm == alg.name {
+				fmt.Fprintf(w, "%.1f,%.1f ", x(pt.n), y(pt.mean))
+			}
+		}
+		fmt.Fprintf(w, "\"/>\n")
+		fmt.Fprintf(w, `<text x="%d" y="%d" fill="%s">%s</text>`+"\n",
+			width-margin-120, margin+16*(i+1), colors[i%len(colors)], alg.name)
+	}
+
+	_, err := fmt.Fprintf(w, "</svg>\n")
+	return err
+}
@@ -23,7 +23,7 @@ func hash64(s string) hash.Hash64 {
 
 func randStr(n int) string {
 	i := rand.Uint32()
-	return fmt.Sprintf("a%s %s", i, n)
+	return fmt.Sprintf("a%d %d", i, n)
 }
 
 func benchmark(precision uint8, n int) {
@@ -72,3 +72,22 @@ func BenchmarkHll14(b *testing.B) {
 func BenchmarkHll16(b *testing.B) {
 	benchmark(16, b.N)
 }
+
+func BenchmarkHllPlusAdd(b *testing.B) {
+	h, _ := NewPlus(14)
+	for i := 0; i < b.N; i++ {
+		h.Add(fakeHash64(uint64(i) * 0x9e3779b97f4a7c15))
+	}
+}
+
+func BenchmarkHllPlusAddHashes(b *testing.B) {
+	h, _ := NewPlus(14)
+	xs := make([]uint64, 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i += len(xs) {
+		for j := range xs {
+			xs[j] = uint64(i+j) * 0x9e3779b97f4a7c15
+		}
+		h.AddHashes(xs)
+	}
+}
@@ -0,0 +1,88 @@
+// Command hllserver serves HyperLogLog++ sketches over the Redis protocol.
+//
+// It implements PFADD, PFCOUNT, PFMERGE, DEL, EXISTS, PING and SAVE, so
+// existing Redis clients and tools can use it as a cardinality service with a
+// precision of their choosing. Elements are hashed with hyperloglog.HashString,
+// so sketches are not interchangeable with those built by Redis itself.
+//
+// Usage:
+//
+//	hllserver [flags]
+//
+// The flags are:
+//
+//	-addr address
+//		address to listen on (default ":6380")
+//	-precision p
+//		precision of new sketches, between 4 and 24 (default 14)
+//	-snapshot file
+//		file to load sketches from at startup and save them to on SAVE,
+//		periodically and at shutdown (default none)
+//	-snapshot-interval duration
+//		how often to save the snapshot, or 0 to only save on SAVE and at
+//		shutdown (default 1m)
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var (
+	addr             = flag.String("addr", ":6380", "address to listen on")
+	precision        = flag.Uint("precision", 14, "precision of new sketches, between 4 and 24")
+	snapshot         = flag.String("snapshot", "", "file to load and save sketches")
+	snapshotInterval = flag.Duration("snapshot-interval", time.Minute, "how often to save the snapshot")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("hllserver: ")
+	flag.Parse()
+
+	if *precision > 255 {
+		log.Fatal("precision must be between 4 and 24")
+	}
+	s, err := newServer(uint8(*precision), *snapshot)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %s", l.Addr())
+
+	if *snapshot != "" && *snapshotInterval > 0 {
+		go func() {
+			for range time.Tick(*snapshotInterval) {
+				if err := s.save(); err != nil {
+					log.Printf("saving snapshot: %v", err)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		<-c
+		l.Close()
+	}()
+
+	if err := s.serve(l); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Fatal(err)
+	}
+	if *snapshot != "" {
+		if err := s.save(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
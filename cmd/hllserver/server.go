@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+// The longest bulk string or array the server accepts from a client.
+const maxBulkLen = 512 << 20
+const maxArrayLen = 1 << 20
+
+// server holds keyed HyperLogLog++ sketches and serves them over the Redis
+// protocol.
+type server struct {
+	mu        sync.Mutex
+	sketches  map[string]*hyperloglog.HyperLogLogPlus
+	precision uint8
+	snapshot  string
+}
+
+func newServer(precision uint8, snapshot string) (*server, error) {
+	if _, err := hyperloglog.NewPlus(precision); err != nil {
+		return nil, err
+	}
+	s := &server{
+		sketches:  make(map[string]*hyperloglog.HyperLogLogPlus),
+		precision: precision,
+		snapshot:  snapshot,
+	}
+	if snapshot != "" {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Loads the sketches in the snapshot file, if it exists.
+func (s *server) load() error {
+	f, err := os.Open(s.snapshot)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := hyperloglog.NewSketchReader(f)
+	for {
+		h := &hyperloglog.HyperLogLogPlus{}
+		key, err := r.Next(h)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.sketches[key] = h
+	}
+}
+
+// Writes all sketches to the snapshot file, replacing it atomically.
+func (s *server) save() error {
+	if s.snapshot == "" {
+		return errors.New("no snapshot file configured")
+	}
+
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.sketches))
+	sketches := make(map[string]*hyperloglog.HyperLogLogPlus, len(s.sketches))
+	for k, h := range s.sketches {
+		keys = append(keys, k)
+		sketches[k] = h.Clone()
+	}
+	s.mu.Unlock()
+	sort.Strings(keys)
+
+	f, err := os.CreateTemp(filepath.Dir(s.snapshot), filepath.Base(s.snapshot)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := hyperloglog.NewSketchWriter(f)
+	for _, k := range keys {
+		if err := w.Write(k, sketches[k]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.snapshot)
+}
+
+// Serves connections from l until it is closed.
+func (s *server) serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if err != io.EOF {
+				writeError(w, "ERR Protocol error: "+err.Error())
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		s.exec(w, args)
+		// Only flush once pipelined commands have all been answered.
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Reads a command, either as an array of bulk strings or inline.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > maxArrayLen {
+		return nil, errors.New("invalid multibulk length")
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("expected '$', got '%.1s'", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxBulkLen {
+			return nil, errors.New("invalid bulk length")
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		if b[size] != '\r' || b[size+1] != '\n' {
+			return nil, errors.New("expected CRLF after bulk string")
+		}
+		args = append(args, string(b[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeError(w *bufio.Writer, msg string) {
+	w.WriteString("-" + msg + "\r\n")
+}
+
+func writeInt(w *bufio.Writer, n int64) {
+	w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	w.WriteString("+" + s + "\r\n")
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+// The minimum and maximum number of arguments of each command, where a maximum
+// of -1 means there is no limit.
+var arity = map[string]struct{ min, max int }{
+	"ping":    {0, 1},
+	"pfadd":   {1, -1},
+	"pfcount": {1, -1},
+	"pfmerge": {1, -1},
+	"del":     {1, -1},
+	"exists":  {1, -1},
+	"save":    {0, 0},
+}
+
+// Runs a command and writes its reply.
+func (s *server) exec(w *bufio.Writer, args []string) {
+	name := strings.ToLower(args[0])
+	args = args[1:]
+
+	a, ok := arity[name]
+	if !ok {
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", name))
+		return
+	}
+	if len(args) < a.min || (a.max >= 0 && len(args) > a.max) {
+		writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+		return
+	}
+
+	if name == "save" {
+		if err := s.save(); err != nil {
+			writeError(w, "ERR "+err.Error())
+			return
+		}
+		writeSimple(w, "OK")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch name {
+	case "ping":
+		if len(args) == 1 {
+			writeBulk(w, args[0])
+		} else {
+			writeSimple(w, "PONG")
+		}
+	case "pfadd":
+		writeInt(w, s.pfadd(args[0], args[1:]))
+	case "pfcount":
+		n, err := s.pfcount(args)
+		if err != nil {
+			writeError(w, "ERR "+err.Error())
+			return
+		}
+		writeInt(w, int64(n))
+	case "pfmerge":
+		if err := s.pfmerge(args[0], args[1:]); err != nil {
+			writeError(w, "ERR "+err.Error())
+			return
+		}
+		writeSimple(w, "OK")
+	case "del":
+		var n int64
+		for _, k := range args {
+			if _, ok := s.sketches[k]; ok {
+				delete(s.sketches, k)
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "exists":
+		var n int64
+		for _, k := range args {
+			if _, ok := s.sketches[k]; ok {
+				n++
+			}
+		}
+		writeInt(w, n)
+	}
+}
+
+// Adds elements to the sketch for key, returning 1 if the sketch was created or
+// changed.
+func (s *server) pfadd(key string, elements []string) int64 {
+	h, ok := s.sketches[key]
+	if !ok {
+		h, _ = hyperloglog.NewPlus(s.precision)
+		s.sketches[key] = h
+	}
+
+	hashes := make([]uint64, len(elements))
+	for i, e := range elements {
+		hashes[i] = hyperloglog.HashString(e)
+	}
+	if h.AddHashesChanged(hashes) || !ok {
+		return 1
+	}
+	return 0
+}
+
+// Returns the estimated cardinality of the union of the sketches for keys.
+func (s *server) pfcount(keys []string) (uint64, error) {
+	var sketches []*hyperloglog.HyperLogLogPlus
+	for _, k := range keys {
+		if h, ok := s.sketches[k]; ok {
+			sketches = append(sketches, h)
+		}
+	}
+	switch len(sketches) {
+	case 0:
+		return 0, nil
+	case 1:
+		return sketches[0].Count(), nil
+	}
+
+	union := sketches[0].Clone()
+	if err := union.MergeAll(sketches[1:]...); err != nil {
+		return 0, err
+	}
+	return union.Count(), nil
+}
+
+// Merges the sketches for srcs into the sketch for dest, creating it if it
+// does not exist.
+func (s *server) pfmerge(dest string, srcs []string) error {
+	h, ok := s.sketches[dest]
+	if ok {
+		h = h.Clone()
+	} else {
+		h, _ = hyperloglog.NewPlus(s.precision)
+	}
+
+	var sketches []*hyperloglog.HyperLogLogPlus
+	for _, k := range srcs {
+		if src, ok := s.sketches[k]; ok {
+			sketches = append(sketches, src)
+		}
+	}
+	if err := h.MergeAll(sketches...); err != nil {
+		return err
+	}
+	s.sketches[dest] = h
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// client is a minimal RESP client.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &client{conn, bufio.NewReader(conn)}
+}
+
+func (c *client) send(args ...string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	c.conn.Write([]byte(b.String()))
+}
+
+// Reads a reply, returning simple strings and errors with their prefix, and
+// integers and bulk strings as strings.
+func (c *client) reply(t *testing.T) string {
+	t.Helper()
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	switch line[0] {
+	case ':':
+		return line[1:]
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			t.Fatal(err)
+		}
+		return string(b[:n])
+	}
+	return line
+}
+
+func (c *client) do(t *testing.T, args ...string) string {
+	t.Helper()
+	c.send(args...)
+	return c.reply(t)
+}
+
+func start(t *testing.T, s *server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go s.serve(l)
+	return l.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	s, err := newServer(14, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := dial(t, start(t, s))
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"PING"}, "+PONG"},
+		{[]string{"ping", "hi"}, "hi"},
+		{[]string{"EXISTS", "a"}, "0"},
+		{[]string{"PFCOUNT", "a"}, "0"},
+		{[]string{"PFADD", "a", "x", "y", "z"}, "1"},
+		{[]string{"PFADD", "a", "x"}, "0"},
+		{[]string{"PFADD", "a"}, "0"},
+		{[]string{"PFADD", "empty"}, "1"},
+		{[]string{"PFCOUNT", "empty"}, "0"},
+		{[]string{"PFCOUNT", "a"}, "3"},
+		{[]string{"PFADD", "b", "z", "w"}, "1"},
+		{[]string{"PFCOUNT", "a", "b", "missing"}, "4"},
+		{[]string{"PFCOUNT", "b"}, "2"},
+		{[]string{"PFMERGE", "c", "a", "b"}, "+OK"},
+		{[]string{"PFCOUNT", "c"}, "4"},
+		{[]string{"PFMERGE", "a", "b"}, "+OK"},
+		{[]string{"PFCOUNT", "a"}, "4"},
+		{[]string{"EXISTS", "a", "a", "b", "missing"}, "3"},
+		{[]string{"DEL", "a", "missing"}, "1"},
+		{[]string{"EXISTS", "a"}, "0"},
+		{[]string{"PFADD"}, "-ERR wrong number of arguments for 'pfadd' command"},
+		{[]string{"PING", "a", "b"}, "-ERR wrong number of arguments for 'ping' command"},
+		{[]string{"GET", "a"}, "-ERR unknown command 'get'"},
+		{[]string{"SAVE"}, "-ERR no snapshot file configured"},
+	} {
+		if got := c.do(t, tc.args...); got != tc.want {
+			t.Errorf("%v = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestServerLargeCount(t *testing.T) {
+	s, _ := newServer(14, "")
+	c := dial(t, start(t, s))
+
+	// Pipeline the adds before reading any replies.
+	for i := 0; i < 100; i++ {
+		args := []string{"PFADD", "k"}
+		for j := 0; j < 1000; j++ {
+			args = append(args, fmt.Sprint("user:", i*1000+j))
+		}
+		c.send(args...)
+	}
+	for i := 0; i < 100; i++ {
+		if r := c.reply(t); r != "1" {
+			t.Error(i, r)
+		}
+	}
+
+	n, _ := strconv.Atoi(c.do(t, "PFCOUNT", "k"))
+	if n < 98000 || n > 102000 {
+		t.Error(n)
+	}
+}
+
+func TestServerInline(t *testing.T) {
+	s, _ := newServer(10, "")
+	c := dial(t, start(t, s))
+	c.conn.Write([]byte("PFADD a x y\r\nPFCOUNT a\r\n"))
+	if r := c.reply(t); r != "1" {
+		t.Error(r)
+	}
+	if r := c.reply(t); r != "2" {
+		t.Error(r)
+	}
+
+	c.conn.Write([]byte("*1\r\n+PING\r\n"))
+	if r := c.reply(t); !strings.HasPrefix(r, "-ERR Protocol error") {
+		t.Error(r)
+	}
+}
+
+func TestServerSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot")
+	s, err := newServer(12, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := dial(t, start(t, s))
+	c.do(t, "PFADD", "a", "x", "y", "z")
+	c.do(t, "PFADD", "b", "x")
+	if r := c.do(t, "SAVE"); r != "+OK" {
+		t.Fatal(r)
+	}
+
+	s, err = newServer(12, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = dial(t, start(t, s))
+	if r := c.do(t, "PFCOUNT", "a"); r != "3" {
+		t.Error(r)
+	}
+	if r := c.do(t, "PFCOUNT", "a", "b"); r != "3" {
+		t.Error(r)
+	}
+
+	// Sketches from a snapshot keep their precision.
+	s, _ = newServer(10, path)
+	c = dial(t, start(t, s))
+	c.do(t, "PFADD", "new", "x")
+	if r := c.do(t, "PFCOUNT", "a", "new"); r != "-ERR precisions must be equal" {
+		t.Error(r)
+	}
+}
+
+func TestServerProtocolErrors(t *testing.T) {
+	s, _ := newServer(10, "")
+	addr := start(t, s)
+
+	for _, req := range []string{
+		"*-1\r\n",
+		"*-5\r\n",
+		"*1\r\n$4\r\nPINGxx",
+		"*2\r\n$4\r\nPING\n$1\r\na\r\n",
+	} {
+		c := dial(t, addr)
+		c.conn.Write([]byte(req))
+		if r := c.reply(t); !strings.HasPrefix(r, "-ERR Protocol error") {
+			t.Errorf("%q: %q", req, r)
+		}
+	}
+
+	// The server is still up.
+	c := dial(t, addr)
+	if r := c.do(t, "PING"); r != "+PONG" {
+		t.Error(r)
+	}
+}
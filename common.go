@@ -1,6 +1,12 @@
 package hyperloglog
 
-import "math"
+import (
+	"math"
+	"math/bits"
+	"runtime"
+	"sort"
+	"sync"
+)
 
 type Hash32 interface {
 	Sum32() uint32
@@ -20,6 +26,21 @@ type set map[uint32]bool
 
 func (s set) Add(i uint32) { s[i] = true }
 
+// Returns the elements of s as a sorted compressedList.
+func (s set) sortedList() *compressedList {
+	keys := make(sortableSlice, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+
+	l := newCompressedList(len(keys))
+	for _, k := range keys {
+		l.Append(k)
+	}
+	return l
+}
+
 func alpha(m uint32) float64 {
 	if m == 16 {
 		return 0.673
@@ -31,54 +52,12 @@ func alpha(m uint32) float64 {
 	return 0.7213 / (1 + 1.079/float64(m))
 }
 
-var clzLookup = []uint8{
-	32, 31, 30, 30, 29, 29, 29, 29, 28, 28, 28, 28, 28, 28, 28, 28,
-}
-
-// This optimized clz32 algorithm is from:
-// 	 http://embeddedgurus.com/state-space/2014/09/
-// 	 		fast-deterministic-and-portable-counting-leading-zeros/
 func clz32(x uint32) uint8 {
-	var n uint8
-
-	if x >= (1 << 16) {
-		if x >= (1 << 24) {
-			if x >= (1 << 28) {
-				n = 28
-			} else {
-				n = 24
-			}
-		} else {
-			if x >= (1 << 20) {
-				n = 20
-			} else {
-				n = 16
-			}
-		}
-	} else {
-		if x >= (1 << 8) {
-			if x >= (1 << 12) {
-				n = 12
-			} else {
-				n = 8
-			}
-		} else {
-			if x >= (1 << 4) {
-				n = 4
-			} else {
-				n = 0
-			}
-		}
-	}
-	return clzLookup[x>>n] - n
+	return uint8(bits.LeadingZeros32(x))
 }
 
 func clz64(x uint64) uint8 {
-	var c uint8
-	for m := uint64(1 << 63); m&x == 0 && m != 0; m >>= 1 {
-		c++
-	}
-	return c
+	return uint8(bits.LeadingZeros64(x))
 }
 
 // Extract bits from uint32 using LSB 0 numbering, including lo.
@@ -93,6 +72,41 @@ func eb64(bits uint64, hi uint8, lo uint8) uint64 {
 	return (bits & m) >> lo
 }
 
+// Minimum number of registers handled by each goroutine in mergeRegisters.
+const minRegistersPerWorker = 1 << 12
+
+// Merges the registers of each of srcs into dst, splitting large register
+// arrays into ranges that are merged in parallel.
+func mergeRegisters(dst []uint8, srcs [][]uint8) {
+	workers := runtime.GOMAXPROCS(0)
+	if n := len(dst) / minRegistersPerWorker; n < workers {
+		workers = n
+	}
+	if workers <= 1 {
+		for _, src := range srcs {
+			maxRegisters(dst, src)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	size := (len(dst) + workers - 1) / workers
+	for lo := 0; lo < len(dst); lo += size {
+		hi := lo + size
+		if hi > len(dst) {
+			hi = len(dst)
+		}
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			for _, src := range srcs {
+				maxRegisters(dst[lo:hi], src[lo:hi])
+			}
+		}(lo, hi)
+	}
+	wg.Wait()
+}
+
 func linearCounting(m uint32, v uint32) float64 {
 	fm := float64(m)
 	return fm * math.Log(fm/float64(v))
@@ -109,12 +123,66 @@ func countZeros(s []uint8) uint32 {
 }
 
 func calculateEstimate(s []uint8) float64 {
-	sum := 0.0
-	for _, val := range s {
-		sum += 1.0 / float64(uint64(1)<<val)
-	}
-
+	sum := sumRegisters(s)
 	m := uint32(len(s))
 	fm := float64(m)
 	return alpha(m) * fm * fm / sum
 }
+
+// Estimates the cardinality from registers s, which hold ranks of at most q+1,
+// using the improved raw estimator from Otmar Ertl, "New cardinality
+// estimation algorithms for HyperLogLog sketches" (2017):
+// https://arxiv.org/abs/1702.01284
+// It needs no empirical bias correction. For small cardinalities its sigma
+// term makes it behave like linear counting.
+func ertlEstimate(s []uint8, q uint8) float64 {
+	c := make([]uint32, q+2)
+	for _, r := range s {
+		if r > q+1 {
+			r = q + 1
+		}
+		c[r]++
+	}
+
+	m := float64(len(s))
+	z := m * tau(1-float64(c[q+1])/m)
+	for k := int(q); k >= 1; k-- {
+		z = 0.5 * (z + float64(c[k]))
+	}
+	z += m * sigma(float64(c[0])/m)
+	return m * m / (2 * math.Ln2 * z)
+}
+
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+
+	y, z := 1.0, x
+	for {
+		x *= x
+		last := z
+		z += x * y
+		y += y
+		if z == last {
+			return z
+		}
+	}
+}
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		last := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == last {
+			return z / 3
+		}
+	}
+}
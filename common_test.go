@@ -1,6 +1,7 @@
 package hyperloglog
 
 import (
+	"bytes"
 	"math"
 	"testing"
 )
@@ -259,3 +260,47 @@ func TestCalculateEstimate(t *testing.T) {
 		t.Error(v)
 	}
 }
+
+func TestMergeRegisters(t *testing.T) {
+	dst := make([]uint8, 1<<16)
+	srcs := make([][]uint8, 3)
+	want := make([]uint8, len(dst))
+	for i := range srcs {
+		srcs[i] = make([]uint8, len(dst))
+		for j := range srcs[i] {
+			v := uint8((j*7 + i*13) % 51)
+			srcs[i][j] = v
+			if v > want[j] {
+				want[j] = v
+			}
+		}
+	}
+
+	mergeRegisters(dst, srcs)
+	if !bytes.Equal(dst, want) {
+		t.Error("merged registers differ")
+	}
+}
+
+func TestErtlEstimate(t *testing.T) {
+	v := ertlEstimate(make([]uint8, 1024), 54)
+	if v != 0 {
+		t.Error(v)
+	}
+
+	// With one register set the estimate is close to linear counting.
+	s := make([]uint8, 1024)
+	s[3] = 1
+	v = ertlEstimate(s, 54)
+	if lc := linearCounting(1024, 1023); math.Abs(v-lc) > 0.01 {
+		t.Error(v, lc)
+	}
+
+	for i := range s {
+		s[i] = 54
+	}
+	v = ertlEstimate(s, 54)
+	if math.IsInf(v, 0) || math.IsNaN(v) || v < 1<<50 {
+		t.Error(v)
+	}
+}
@@ -1,5 +1,7 @@
 package hyperloglog
 
+import "container/heap"
+
 type iterable interface {
 	decode(i int, last uint32) (uint32, int)
 	Len() int
@@ -59,6 +61,77 @@ func (v *compressedList) Iter() *iterator {
 	return &iterator{0, 0, v}
 }
 
+// containsAll reports whether the list contains every one of keys, which must
+// be sorted. It stops decoding at the first key that is missing.
+func (v *compressedList) containsAll(keys []uint32) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	if v.Count == 0 || keys[len(keys)-1] > v.last {
+		return false
+	}
+
+	iter := v.Iter()
+	for _, k := range keys {
+		for iter.HasNext() && iter.Peek() < k {
+			iter.Next()
+		}
+		if !iter.HasNext() || iter.Peek() != k {
+			return false
+		}
+	}
+	return true
+}
+
+// iteratorHeap is a min-heap of iterators ordered by their next value, used
+// for k-way merges of sorted lists.
+type iteratorHeap []*iterator
+
+func (h iteratorHeap) Len() int            { return len(h) }
+func (h iteratorHeap) Less(i, j int) bool  { return h[i].Peek() < h[j].Peek() }
+func (h iteratorHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *iteratorHeap) Push(x interface{}) { *h = append(*h, x.(*iterator)) }
+
+func (h *iteratorHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// mergeLists merges sorted lists into a single sorted list without
+// duplicates. It gives up and returns false as soon as the merged list grows
+// longer than max bytes.
+func mergeLists(max int, lists ...*compressedList) (*compressedList, bool) {
+	h := make(iteratorHeap, 0, len(lists))
+	for _, l := range lists {
+		if iter := l.Iter(); iter.HasNext() {
+			h = append(h, iter)
+		}
+	}
+	heap.Init(&h)
+
+	newList := newCompressedList(max)
+	for len(h) > 0 {
+		iter := h[0]
+		x := iter.Next()
+		if newList.Count == 0 || x != newList.last {
+			newList.Append(x)
+			if newList.Len() > max {
+				return nil, false
+			}
+		}
+
+		if iter.HasNext() {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return newList, true
+}
+
 type variableLengthList []uint8
 
 func (v variableLengthList) Len() int {
@@ -72,10 +145,13 @@ func (v *variableLengthList) Iter() *iterator {
 func (v variableLengthList) decode(i int, last uint32) (uint32, int) {
 	var x uint32
 	j := i
-	for ; v[j]&0x80 != 0; j++ {
+	for ; j < len(v) && v[j]&0x80 != 0; j++ {
 		x |= uint32(v[j]&0x7f) << (uint(j-i) * 7)
 	}
-	x |= uint32(v[j]) << (uint(j-i) * 7)
+	// A truncated value ends the list.
+	if j < len(v) {
+		x |= uint32(v[j]) << (uint(j-i) * 7)
+	}
 	return x, j + 1
 }
 
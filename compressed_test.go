@@ -2,6 +2,7 @@ package hyperloglog
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 )
 
@@ -127,3 +128,33 @@ func TestCompressedList(t *testing.T) {
 		t.Error(l)
 	}
 }
+
+func TestMergeLists(t *testing.T) {
+	a, b, c := newCompressedList(0), newCompressedList(0), newCompressedList(0)
+	for _, x := range []uint32{1, 5, 9} {
+		a.Append(x)
+	}
+	for _, x := range []uint32{2, 5, 300} {
+		b.Append(x)
+	}
+
+	l, ok := mergeLists(100, a, b, c)
+	if !ok {
+		t.Fatal("merge should fit")
+	}
+	var got []uint32
+	for iter := l.Iter(); iter.HasNext(); {
+		got = append(got, iter.Next())
+	}
+	if !reflect.DeepEqual(got, []uint32{1, 2, 5, 9, 300}) {
+		t.Error(got)
+	}
+
+	// The merged list takes 6 bytes, since 300 needs two.
+	if _, ok := mergeLists(5, a, b); ok {
+		t.Error("merge should not fit in 5 bytes")
+	}
+	if _, ok := mergeLists(6, a, b); !ok {
+		t.Error("merge should fit in 6 bytes")
+	}
+}
@@ -0,0 +1,134 @@
+package hyperloglog
+
+import "errors"
+
+// CountingHyperLogLog is a HyperLogLog++ sketch that supports removals. For
+// each register it keeps a saturating 8-bit counter for every possible rank,
+// counting the adds with that register index and rank. The value of a register
+// is the highest rank whose counter is not zero, so it falls back when the
+// last item with that rank is removed.
+//
+// Adds and removes must balance: an item added twice is counted until it has
+// been removed twice. While no counter has saturated, a sketch whose removals
+// all match earlier adds estimates exactly as a HyperLogLogPlus that only saw
+// the remaining items. A counter that reaches 255 no longer knows how many adds
+// it holds, so it stays at 255, and removing items with its index and rank has
+// no effect; the estimate can then be too high. Removing an item that was never
+// added returns an error if its counter is zero, and otherwise silently removes
+// an item that shares its index and rank.
+//
+// There is no sparse representation, and each register takes 65-p bytes where
+// a normal HyperLogLogPlus takes one: about 816KB at precision 14 rather than
+// 16KB.
+type CountingHyperLogLog struct {
+	p uint8
+	m uint32
+
+	// The counter for register i and rank r is at i*ranks+r-1.
+	ranks    uint32
+	counters []uint8
+}
+
+// The highest value of a counter, which it never leaves.
+const counterSaturated = 255
+
+// NewCounting returns a new initialized CountingHyperLogLog.
+func NewCounting(precision uint8) (*CountingHyperLogLog, error) {
+	if precision > 18 || precision < 4 {
+		return nil, errors.New("precision must be between 4 and 18")
+	}
+
+	h := &CountingHyperLogLog{}
+	h.p = precision
+	h.m = 1 << precision
+	h.ranks = 65 - uint32(precision)
+	h.counters = make([]uint8, h.m*h.ranks)
+	return h, nil
+}
+
+// Clear sets CountingHyperLogLog h back to its initial state.
+func (h *CountingHyperLogLog) Clear() {
+	h.counters = make([]uint8, h.m*h.ranks)
+}
+
+// Returns the position of the counter for hash x.
+func (h *CountingHyperLogLog) counter(x uint64) uint32 {
+	i := uint32(x >> (64 - h.p)) // {x63,...,x64-p}
+	w := x<<h.p | 1<<(h.p-1)     // {x63-p,...,x0}
+
+	zeroBits := clz64(w) + 1
+	return i*h.ranks + uint32(zeroBits) - 1
+}
+
+// Add adds a new item to CountingHyperLogLog h.
+func (h *CountingHyperLogLog) Add(item Hash64) {
+	h.addHash(item.Sum64())
+}
+
+func (h *CountingHyperLogLog) addHash(x uint64) {
+	c := &h.counters[h.counter(x)]
+	if *c < counterSaturated {
+		*c++
+	}
+}
+
+// AddHashes adds a batch of already hashed items to CountingHyperLogLog h.
+func (h *CountingHyperLogLog) AddHashes(xs []uint64) {
+	for _, x := range xs {
+		h.addHash(x)
+	}
+}
+
+// Remove removes an item previously added to CountingHyperLogLog h. It returns
+// an error, and leaves h unchanged, if no added item has the same register
+// index and rank.
+func (h *CountingHyperLogLog) Remove(item Hash64) error {
+	c := &h.counters[h.counter(item.Sum64())]
+	switch *c {
+	case 0:
+		return errors.New("item was not added")
+	case counterSaturated:
+	default:
+		*c--
+	}
+	return nil
+}
+
+// Merge takes another CountingHyperLogLog and adds its counters to those of
+// CountingHyperLogLog h, as if h had seen the adds and removes of both. Items
+// added to both must be removed from h twice.
+func (h *CountingHyperLogLog) Merge(other *CountingHyperLogLog) error {
+	if h.p != other.p {
+		return errors.New("precisions must be equal")
+	}
+
+	for i, c := range other.counters {
+		if s := uint32(h.counters[i]) + uint32(c); s < counterSaturated {
+			h.counters[i] = uint8(s)
+		} else {
+			h.counters[i] = counterSaturated
+		}
+	}
+	return nil
+}
+
+// Returns the registers of the equivalent HyperLogLog++ sketch.
+func (h *CountingHyperLogLog) registers() []uint8 {
+	reg := make([]uint8, h.m)
+	for i := range reg {
+		cs := h.counters[uint32(i)*h.ranks : uint32(i+1)*h.ranks]
+		for r := len(cs); r > 0; r-- {
+			if cs[r-1] != 0 {
+				reg[i] = uint8(r)
+				break
+			}
+		}
+	}
+	return reg
+}
+
+// Count returns the cardinality estimate, using the same estimator as a
+// HyperLogLogPlus in the normal representation.
+func (h *CountingHyperLogLog) Count() uint64 {
+	return estimateRegisters(h.registers(), h.p)
+}
@@ -0,0 +1,140 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCountingNew(t *testing.T) {
+	for _, p := range []uint8{3, 19} {
+		if _, err := NewCounting(p); err == nil {
+			t.Error("expected error for precision", p)
+		}
+	}
+	h, err := NewCounting(14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.counters) != 16384*51 {
+		t.Error(len(h.counters))
+	}
+}
+
+func TestCountingAddRemove(t *testing.T) {
+	h, _ := NewCounting(12)
+	want, _ := NewPlus(12)
+	want.toNormal()
+
+	for i := uint64(0); i < 20000; i++ {
+		h.Add(fakeHash64(HashUint64(i)))
+	}
+	for i := uint64(0); i < 20000; i += 2 {
+		if err := h.Remove(fakeHash64(HashUint64(i))); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+	for i := uint64(1); i < 20000; i += 2 {
+		want.Add(fakeHash64(HashUint64(i)))
+	}
+
+	if !bytes.Equal(h.registers(), want.reg) {
+		t.Error("registers differ from a sketch of the remaining items")
+	}
+	if h.Count() != want.Count() {
+		t.Error(h.Count(), want.Count())
+	}
+	if n := h.Count(); n < 9500 || n > 10500 {
+		t.Error(n)
+	}
+
+	for i := uint64(1); i < 20000; i += 2 {
+		h.Remove(fakeHash64(HashUint64(i)))
+	}
+	if n := h.Count(); n != 0 {
+		t.Error(n)
+	}
+}
+
+func TestCountingRemoveNotAdded(t *testing.T) {
+	h, _ := NewCounting(8)
+	h.Add(fakeHash64(0x00ff000000000000))
+	if err := h.Remove(fakeHash64(0x0100000000000000)); err == nil {
+		t.Error("expected error")
+	}
+	if h.Count() != 1 {
+		t.Error(h.Count())
+	}
+
+	// Same index and rank as the added item.
+	if err := h.Remove(fakeHash64(0x00ff000000000001)); err != nil {
+		t.Error(err)
+	}
+	if h.Count() != 0 {
+		t.Error(h.Count())
+	}
+}
+
+func TestCountingSaturation(t *testing.T) {
+	h, _ := NewCounting(8)
+	x := fakeHash64(0x00ff000000000000)
+	for i := 0; i < 300; i++ {
+		h.Add(x)
+	}
+	for i := 0; i < 300; i++ {
+		if err := h.Remove(x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if h.Count() != 1 {
+		t.Error("saturated counter was decremented")
+	}
+
+	y := fakeHash64(0x0100000000000000)
+	for i := 0; i < 10; i++ {
+		h.Add(y)
+	}
+	for i := 0; i < 10; i++ {
+		h.Remove(y)
+	}
+	if h.Count() != 1 {
+		t.Error(h.Count())
+	}
+}
+
+func TestCountingMerge(t *testing.T) {
+	h, _ := NewCounting(10)
+	other, _ := NewCounting(10)
+	for i := uint64(0); i < 1000; i++ {
+		h.Add(fakeHash64(HashUint64(i)))
+		other.Add(fakeHash64(HashUint64(i + 500)))
+	}
+	if err := h.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if n := h.Count(); n < 1400 || n > 1600 {
+		t.Error(n)
+	}
+
+	// Items in both sketches were added twice.
+	for i := uint64(0); i < 1500; i++ {
+		h.Remove(fakeHash64(HashUint64(i)))
+	}
+	if n := h.Count(); n < 450 || n > 550 {
+		t.Error(n)
+	}
+
+	other.Clear()
+	for i := 0; i < 200; i++ {
+		h.Add(fakeHash64(0))
+		other.Add(fakeHash64(0))
+	}
+	h.Merge(other)
+	if c := h.counters[h.counter(0)]; c != counterSaturated {
+		t.Error(c)
+	}
+
+	small, _ := NewCounting(9)
+	if err := h.Merge(small); err == nil {
+		t.Error("expected precision error")
+	}
+}
@@ -0,0 +1,41 @@
+// Code generated by "go run gendata.go -trials 100 -o data_simulated.go"; DO NOT EDIT.
+
+package hyperloglog
+
+// Bias correction tables in the form of those in data.go, simulated with 100
+// uniform streams per precision and indexed by precision - simulatedMinPrecision.
+const simulatedMinPrecision = 19
+
+// Cardinalities below which linear counting is more accurate than the bias
+// corrected estimate.
+var simulatedThreshold = []uint{366997, 262141, 1153417, 1467999, 8178886, 19293781}
+
+var simulatedRawEstimateData = [][]float64{
+	// precision 19
+	{378168.627, 384509.2417, 390925.7521, 397416.2993, 403982.5938, 410625.0185, 417346.7098, 424147.3536, 431010.6121, 437956.7708, 444970.6374, 452070.0388, 459237.5087, 466484.829, 473809.5686, 481206.3553, 488666.5529, 496216.9373, 503843.8969, 511540.4303, 519319.1994, 527169.1668, 535082.5598, 543076.1327, 551148.6049, 559282.9884, 567505.0363, 575796.5845, 584158.2918, 592594.2501, 601107.9431, 609687.8291, 618326.7142, 627064.7191, 635873.8527, 644735.5246, 653649.919, 662661.9269, 671711.9274, 680868.9899, 690077.8275, 699351.633, 708669.8451, 718065.4725, 727535.7875, 737078.5852, 746647.9734, 756322.9737, 766050.1735, 775819.92, 785680.6625, 795586.7672, 805559.7749, 815601.8203, 825673.2362, 835837.5237, 846049.1261, 856319.2363, 866633.0743, 877012.1228, 887446.2944, 897943.2168, 908503.5983, 919094.7927, 929741.7866, 940416.0022, 951162.9775, 961944.0855, 972784.3501, 983705.869, 994663.5803, 1005668.2408, 1016705.3566, 1027770.0787, 1038903.2634, 1050132.7316, 1061316.1842, 1072562.1971, 1083887.2338, 1095228.0578, 1106625.4029, 1118090.733, 1129581.231, 1141094.7636, 1152675.8351, 1164217.5546, 1175813.8099, 1187480.983, 1199200.5922, 1210894.0715, 1222642.1819, 1234486.1025, 1246331.6074, 1258201.5227, 1270107.5919, 1282081.4059, 1294025.9296, 1306050.5863, 1318119.9407, 1330247.0339, 1342340.8273, 1354430.2904, 1366580.6879, 1378756.9551, 1390901.4395, 1403145.8149, 1415392.1104, 1427643.2949, 1439946.5452, 1452239.2911, 1464625.6597, 1476960.1694, 1489362.0838, 1501741.9984, 1514157.0482, 1526652.9569, 1539160.3025, 1551617.4902, 1564158.593, 1576715.4791, 1589260.1569, 1601765.1027, 1614310.2086, 1626896.826, 1639479.1262, 1652076.7277, 1664723.9871, 1677349.4623, 1689949.3697, 1702590.886, 1715303.6629, 1728060.9359, 1740809.1795, 1753548.3655, 1766241.9191, 1778961.0256, 1791796.279, 1804573.0908, 1817413.3421, 1830177.7159, 1842962.0598, 1855760.4455, 1868641.6401, 1881477.5012, 1894309.849, 1907130.4618, 1919937.9183, 1932865.1612, 1945813.2427, 1958637.3011, 1971514.1932, 1984404.4313, 1997353.6802, 2010302.7775, 2023252.6812, 2036119.2294, 2049112.8577, 2062112.1006, 2075098.4291, 2088127.9576, 2101082.8183, 2114044.6272, 2126936.4376, 2139897.8806, 2152848.9179, 2165811.5177, 2178822.3222, 2191823.7414, 2204810.2497, 2217823.5156, 2230844.0885, 2243895.6981, 2256955.0814, 2270027.4045, 2283012.262, 2296101.7463, 2309128.0815, 2322213.6794, 2335241.6841, 2348278.88, 2361326.5215, 2374360.7583, 2387480.9347, 2400553.7633, 2413561.7782, 2426592.6558, 2439618.433, 2452655.9298, 2465814.9007, 2478799.3236, 2491849.2367, 2504962.0899, 2518034.5156, 2531106.4438, 2544155.2156, 2557207.9454, 2570289.384, 2583297.3293, 2596458.1789, 2609476.0319, 2622558.1212},
+	// precision 20
+	{756337.554, 769017.9952, 781848.0035, 794834.1536, 807966.7231, 821254.5978, 834687.7433, 848281.6497, 862012.017, 875909.6744, 889948.557, 904144.6146, 918484.0276, 932983.5951, 947636.3338, 962435.1627, 977373.9928, 992472.4961, 1007722.54, 1023109.9887, 1038657.2637, 1054356.3589, 1070197.2584, 1086178.4446, 1102320.5513, 1118613.2741, 1135052.3065, 1151638.3922, 1168374.897, 1185239.5173, 1202258.3918, 1219431.2717, 1236703.2964, 1254153.1315, 1271694.6773, 1289435.1928, 1307305.679, 1325303.1494, 1343428.8205, 1361717.0349, 1380129.0565, 1398670.1069, 1417342.203, 1436110.8128, 1455071.9302, 1474118.408, 1493322.189, 1512656.1203, 1532099.4849, 1551720.0128, 1571412.5874, 1591247.2748, 1611183.273, 1631286.3364, 1651477.7978, 1671784.4948, 1692199.2872, 1712716.2307, 1733354.5933, 1754132.355, 1775060.6185, 1796026.8437, 1817111.7553, 1838300.0991, 1859555.8223, 1880960.6868, 1902522.7991, 1924152.9119, 1945882.3029, 1967711.1015, 1989591.6086, 2011640.1707, 2033735.3105, 2055843.9693, 2078167.784, 2100524.3368, 2123001.5421, 2145574.8729, 2168213.2161, 2190983.8433, 2213784.8628, 2236608.9077, 2259593.5198, 2282627.6564, 2305748.4924, 2328927.5636, 2352248.8629, 2375553.8142, 2398990.1473, 2422441.6806, 2445997.3446, 2469612.5173, 2493311.9339, 2517094.2812, 2540926.8321, 2564789.2298, 2588747.0363, 2612726.7949, 2636763.2863, 2660890.7273, 2685044.1227, 2709233.6152, 2733510.8636, 2757862.2008, 2782197.8646, 2806685.0307, 2831150.6088, 2855656.9435, 2880173.6179, 2904733.7019, 2929450.003, 2954254.5811, 2979020.7661, 3003861.717, 3028754.1542, 3053630.7174, 3078472.596, 3103472.4465, 3128411.3579, 3153425.4637, 3178513.4743, 3203572.1124, 3228647.4553, 3253782.6316, 3279014.0244, 3304260.7173, 3329490.8232, 3354771.1474, 3380129.2734, 3405461.2624, 3430837.2626, 3456277.5042, 3481712.8928, 3507112.4496, 3532629.0428, 3558141.1763, 3583625.1461, 3609205.5387, 3634737.9611, 3660324.7593, 3685898.751, 3711410.3939, 3737097.7681, 3762856.6448, 3788548.8378, 3814301.2028, 3839997.8347, 3865778.9171, 3891378.9768, 3917126.3656, 3942841.73, 3968598.3595, 3994469.5984, 4020235.9011, 4046138.5538, 4071960.9685, 4097744.7349, 4123678.0772, 4149481.51, 4175246.8334, 4201080.6985, 4226898.7164, 4252819.8805, 4278814.4642, 4304654.6499, 4330632.8119, 4356583.5037, 4382532.4773, 4408524.3874, 4434573.3384, 4460663.71, 4486569.4412, 4512533.6558, 4538627.0925, 4564643.2494, 4590674.8683, 4616822.1135, 4642857.8674, 4668896.5711, 4695004.4666, 4721088.3121, 4747158.6471, 4773245.0212, 4799414.6575, 4825601.5166, 4851762.7355, 4877823.2456, 4904012.9173, 4930165.2542, 4956351.4648, 4982426.2754, 5008548.1458, 5034695.812, 5060801.8073, 5086799.6393, 5113081.2132, 5139250.0279, 5165358.0608, 5191542.1768, 5217652.8467, 5243783.1595},
+	// precision 21
+	{1512675.4543, 1538039.0808, 1563700.5337, 1589667.2187, 1615933.7733, 1642498.0561, 1669370.0485, 1696550.3673, 1724031.0398, 1751799.8154, 1779868.1924, 1808256.2286, 1836944.7959, 1865938.4108, 1895245.9692, 1924818.7683, 1954698.5076, 1984865.0242, 2015387.3606, 2046188.1687, 2077296.7417, 2108695.5958, 2140378.5252, 2172369.5109, 2204654.4594, 2237261.1194, 2270103.1814, 2303288.9533, 2336721.2391, 2370423.0533, 2404476.9842, 2438797.0196, 2473351.4716, 2508257.5085, 2543439.9178, 2578908.5618, 2614655.6625, 2650624.9672, 2686927.4152, 2723451.4407, 2760244.9151, 2797315.9571, 2834697.1515, 2872268.5648, 2910108.7888, 2948225.5492, 2986627.5458, 3025256.9144, 3064135.426, 3103271.3483, 3142662.318, 3182309.462, 3222203.6337, 3262322.064, 3302624.1069, 3343210.5212, 3384082.2513, 3425157.0801, 3466489.882, 3507981.281, 3549729.8233, 3591620.9707, 3633758.3347, 3676125.9155, 3718731.635, 3761563.3081, 3804641.792, 3847910.5348, 3891334.4376, 3934942.7648, 3978767.3114, 4022768.22, 4066991.7748, 4111379.3427, 4155939.3619, 4200711.8283, 4245603.27, 4290743.509, 4335952.8192, 4381360.1012, 4426810.2404, 4472589.458, 4518509.2378, 4564504.1172, 4610772.4787, 4657179.9442, 4703632.8865, 4750364.5462, 4797240.4771, 4844209.1631, 4891298.8923, 4938613.4203, 4986030.5434, 5033503.5274, 5081128.089, 5128897.1652, 5176755.417, 5224695.5885, 5272788.5801, 5321016.6621, 5369310.2848, 5417678.6551, 5466282.6249, 5514890.3908, 5563715.1849, 5612546.5238, 5661511.6233, 5710639.4704, 5759810.0328, 5809054.9963, 5858526.5537, 5908023.6492, 5957562.4701, 6007244.0306, 6056957.9454, 6106688.9923, 6156581.1914, 6206607.7271, 6256545.4184, 6306615.1711, 6356671.5408, 6406826.7637, 6457178.7558, 6507525.6753, 6558010.1168, 6608484.172, 6658965.9981, 6709529.4057, 6760157.449, 6810834.3012, 6861651.562, 6912375.1143, 6963242.8083, 7014133.3159, 7064992.8749, 7115907.5691, 7166885.8297, 7218129.6792, 7269271.246, 7320608.691, 7371860.1789, 7423176.2147, 7474518.7314, 7525935.1301, 7577172.7489, 7628526.8047, 7679891.6109, 7731459.2937, 7782969.3703, 7834438.7788, 7885925.5408, 7937583.6255, 7989293.9668, 8040908.9538, 8092612.3892, 8144142.9551, 8195993.4847, 8247618.0747, 8299446.4655, 8351380.4881, 8403193.6332, 8455159.387, 8506985.0414, 8559004.5445, 8610880.2823, 8662766.691, 8714575.5433, 8766548.0555, 8818481.7796, 8870586.8645, 8922588.9367, 8974578.6291, 9026524.9678, 9078673.6288, 9130676.2184, 9182780.5872, 9234935.9951, 9287312.2633, 9339433.3308, 9391509.8022, 9443802.7297, 9495895.8972, 9548049.5514, 9600219.2358, 9652447.3967, 9704509.0607, 9756912.9466, 9809072.0614, 9861375.7381, 9913615.2164, 9965689.8333, 10017832.9357, 10070305.6037, 10122394.5322, 10174513.3618, 10226888.0145, 10279243.1997, 10331350.4375, 10383642.8188, 10435780.1489, 10488022.9686},
+	// precision 22
+	{3025351.1924, 3076074.5825, 3127395.1723, 3179314.6285, 3231856.5256, 3284976.1395, 3338734.1256, 3393091.9331, 3448031.2975, 3503583.5421, 3559744.3667, 3616518.7374, 3673899.6263, 3731869.801, 3790453.1593, 3849634.1535, 3909419.0977, 3969816.4008, 4030825.6534, 4092411.024, 4154587.1207, 4217358.416, 4280740.0891, 4344717.4903, 4409292.8119, 4474420.2489, 4540154.639, 4606436.2123, 4673344.7847, 4740816.9857, 4808844.7337, 4877430.9444, 4946631.0492, 5016398.3106, 5086742.0008, 5157625.0349, 5229068.7891, 5301093.4172, 5373634.3153, 5446753.9466, 5520353.8511, 5594519.8747, 5669123.1138, 5744320.7763, 5820117.5054, 5896435.2601, 5973227.7028, 6050559.6027, 6128453.671, 6206741.8114, 6285511.1065, 6364827.0468, 6444619.7679, 6524888.4903, 6605632.7632, 6686925.5116, 6768617.2642, 6850740.6554, 6933309.3421, 7016372.5774, 7099807.2304, 7183732.673, 7268088.0627, 7352876.5531, 7438013.1167, 7523556.5195, 7609568.7163, 7695942.2642, 7782710.1391, 7869931.2048, 7957594.4544, 8045560.1942, 8133907.4981, 8222642.8332, 8311782.8867, 8401193.7575, 8491106.0273, 8581287.5767, 8671795.3291, 8762603.4, 8853858.3402, 8945441.1528, 9037401.5504, 9129541.6003, 9221977.7808, 9314745.7033, 9407749.0001, 9501142.0259, 9594860.3303, 9688951.2426, 9783277.2347, 9877770.4558, 9972612.2087, 10067645.5801, 10162905.8014, 10258320.2476, 10354125.8727, 10450054.6523, 10546421.1412, 10642820.6453, 10739377.4239, 10836248.5996, 10933268.3266, 11030697.0181, 11128358.5804, 11226115.4064, 11324170.4046, 11422287.1031, 11520461.3968, 11619017.2105, 11717676.6145, 11816529.1131, 11915600.9594, 12014763.7781, 12114326.6418, 12213919.3742, 12313732.7192, 12413639.7168, 12513544.7964, 12613732.8885, 12714022.223, 12814472.64, 12915092.4382, 13015721.9686, 13116539.1935, 13217307.5496, 13318346.8701, 13419451.5998, 13520778.2783, 13622021.0387, 13723564.7662, 13825266.8189, 13927070.6922, 14028869.2086, 14130759.9482, 14232756.3178, 14334924.1433, 14437132.4435, 14539540.0839, 14641716.4817, 14744196.1194, 14846731.9549, 14949293.6912, 15051856.0531, 15154563.3859, 15257593.3333, 15360375.9687, 15463376.4137, 15566477.2759, 15669650.7464, 15772951.7712, 15876152.8767, 15979257.9927, 16082639.6138, 16185977.1938, 16289283.2902, 16392752.704, 16496247.1807, 16599755.337, 16703476.8735, 16807214.8001, 16910958.1831, 17014726.6598, 17118324.2586, 17222317.3211, 17326367.7843, 17430055.8428, 17533861.6318, 17637749.9637, 17742036.9619, 17846196.5146, 17950040.8195, 18054177.7608, 18158202.8526, 18262450.5644, 18366929.794, 18471155.4251, 18575188.354, 18679505.9516, 18783746.8015, 18887892.16, 18992443.0238, 19096939.9985, 19201076.5822, 19305617.4701, 19410209.3147, 19514408.0178, 19619089.0319, 19723506.0649, 19827852.4953, 19932187.0742, 20036822.0462, 20141211.6488, 20245713.5843, 20350263.7004, 20454535.1669, 20559084.6736, 20663492.6883, 20768048.0559, 20872626.4885, 20977064.6293},
+	// precision 23
+	{6050702.6482, 6152139.6371, 6254783.383, 6358640.2891, 6463701.0091, 6569957.4575, 6677442.0626, 6786118.102, 6896035.3703, 7007168.6801, 7119494.2813, 7233030.8273, 7347767.2757, 7463700.1798, 7580859.7884, 7699220.7815, 7818786.5713, 7939580.62, 8061543.8424, 8184729.1109, 8309122.9026, 8434617.223, 8561344.2922, 8689273.4891, 8818418.3345, 8948722.4495, 9080250.9277, 9212865.2141, 9346651.5858, 9481575.2119, 9617647.3819, 9754895.2746, 9893280.4565, 10032736.7598, 10173355.9358, 10315112.2345, 10457975.0462, 10601990.3058, 10747138.8446, 10893363.111, 11040560.29, 11188935.9117, 11338346.2729, 11488802.9296, 11640351.5417, 11792982.4256, 11946696.9741, 12101260.9884, 12256836.1229, 12413466.6591, 12571101.1036, 12729631.6753, 12889311.7633, 13049866.5594, 13211191.0121, 13373552.2121, 13536876.6963, 13701184.736, 13866507.1857, 14032580.2674, 14199444.6263, 14367328.1446, 14535958.7804, 14705404.0182, 14875786.8329, 15047120.0106, 15219185.7321, 15392040.9977, 15565842.1309, 15740258.1878, 15915488.4735, 16091534.5771, 16268196.6767, 16445853.52, 16624209.9187, 16803219.9143, 16982937.7859, 17163356.2393, 17344378.9874, 17526058.2187, 17708410.0107, 17891565.6718, 18075189.1783, 18259677.8759, 18444485.2086, 18630163.5482, 18816258.6543, 19002875.4582, 19190370.8261, 19378166.8785, 19566600.6545, 19755594.1171, 19945265.2337, 20135228.1735, 20325820.9056, 20516831.6976, 20708393.3777, 20900343.0129, 21092733.0372, 21285738.0471, 21479324.7918, 21672946.3371, 21867043.3275, 22061803.9103, 22256629.8447, 22452161.6088, 22647978.9233, 22844189.7786, 23041125.2452, 23238176.7612, 23435449.4077, 23633025.1984, 23831301.8604, 24029545.908, 24228392.1059, 24427447.6052, 24626678.1452, 24826359.7491, 25026328.6086, 25226782.3475, 25427649.703, 25628385.3606, 25829405.7587, 26030774.2953, 26232433.7338, 26434254.2118, 26636319.5709, 26838576.3748, 27041074.9252, 27243803.609, 27446807.3028, 27650127.3965, 27853648.4106, 28057264.8457, 28261570.7667, 28465797.0061, 28670058.685, 28874202.9953, 29078920.1875, 29283686.2918, 29488807.5371, 29693566.4218, 29898958.8117, 30104525.6875, 30310287.7806, 30515994.6799, 30721549.6416, 30927429.116, 31133612.8987, 31339758.7525, 31545832.7407, 31752259.5265, 31958456.3065, 32164988.7236, 32371836.5223, 32578797.2159, 32785599.7845, 32992504.6291, 33199612.349, 33406671.2936, 33614065.7926, 33821471.5656, 34028950.1868, 34236799.5815, 34444519.9584, 34652349.8355, 34859745.3375, 35067653.2473, 35275701.7041, 35483961.4639, 35692123.3626, 35900291.6907, 36108612.0681, 36316733.4043, 36524839.0523, 36733077.9545, 36941663.617, 37149686.6169, 37358217.944, 37566744.147, 37775231.2814, 37983943.9677, 38192636.8109, 38401528.8007, 38610329.7639, 38819225.6202, 39028022.2032, 39236799.2479, 39445767.4831, 39654516.1327, 39863254.9586, 40072477.472, 40281645.4081, 40490720.9983, 40699803.7201, 40908883.1734, 41117801.6683, 41326923.6477, 41536045.8251, 41745004.14, 41954401.498},
+	// precision 24
+	{12101405.595, 12304269.423, 12509543.0116, 12717228.8114, 12927356.7763, 13139890.3628, 13354838.6771, 13572203.6378, 13791987.2312, 14014200.6704, 14238860.1204, 14465896.2154, 14695382.0339, 14927275.4147, 15161585.8408, 15398283.4802, 15637451.6376, 15878974.1494, 16122927.5691, 16369321.0773, 16618021.6176, 16869208.1159, 17122717.0151, 17378674.6725, 17636861.8806, 17897428.3672, 18160291.4088, 18425547.8029, 18693167.6273, 18963167.944, 19235359.2495, 19509833.1095, 19786555.4078, 20065614.6406, 20346882.2414, 20630370.5498, 20916025.7347, 21204071.0488, 21494320.2513, 21786641.1566, 22081032.3686, 22377667.2375, 22676400.5852, 22977320.6194, 23280289.9706, 23585366.9146, 23892626.2418, 24201825.4559, 24513033.8668, 24826300.4471, 25141626.3573, 25458559.1739, 25777756.2231, 26098875.1766, 26421856.2241, 26746726.8648, 27073462.4431, 27401971.906, 27732292.664, 28064497.8594, 28398510.0572, 28734092.4227, 29071691.927, 29410889.9432, 29751613.7547, 30094045.8977, 30438432.6353, 30784340.4764, 31131612.6041, 31480613.9252, 31831058.4777, 32183046.3547, 32536716.1012, 32891832.3636, 33248211.1875, 33606001.2422, 33965218.854, 34325884.8386, 34688100.4107, 35051629.5305, 35416426.442, 35782716.2858, 36150323.2994, 36518885.4343, 36888872.1934, 37260022.4658, 37632559.9091, 38005978.7662, 38380681.1648, 38756421.7198, 39133357.3838, 39511393.0099, 39890345.5405, 40270552.8675, 40651585.0881, 41033385.5592, 41416621.2362, 41800837.5154, 42185778.7905, 42571716.4178, 42958557.619, 43346463.2246, 43734889.5484, 44124182.0259, 44514607.2447, 44905808.9154, 45297603.6026, 45690285.6592, 46083668.4739, 46477789.4334, 46872904.2877, 47268568.691, 47665038.0425, 48061855.3877, 48459017.9952, 48856810.7246, 49255728.8213, 49654948.8969, 50055263.3975, 50455726.8389, 50856672.661, 51258542.3783, 51660560.4898, 52063671.9737, 52466844.2079, 52870446.2955, 53274658.1171, 53679830.018, 54084891.6942, 54490707.1322, 54896741.5174, 55303496.4576, 55710553.1231, 56118067.9319, 56525758.6645, 56934118.4732, 57342653.5212, 57751995.5573, 58161040.9775, 58570594.8578, 58980310.1817, 59390319.0221, 59800520.7551, 60211093.3725, 60622086.0009, 61033420.355, 61444683.0852, 61856487.2245, 62268623.0052, 62681037.0235, 63093366.2438, 63505967.2832, 63919233.8348, 64332384.6683, 64746018.7277, 65159141.2821, 65572907.1031, 65987425.0465, 66402090.655, 66816298.091, 67230908.1082, 67645378.8644, 68060215.9365, 68475206.6974, 68890314.2867, 69305815.6865, 69721332.4299, 70136942.4291, 70553043.9775, 70968940.2964, 71385556.5272, 71801820.1269, 72218542.6722, 72635155.5456, 73051876.7246, 73468569.8492, 73885537.8071, 74302430.117, 74719208.0132, 75136140.7374, 75552938.0457, 75970105.2906, 76387334.5523, 76804776.1007, 77222135.5336, 77639570.5588, 78057206.8557, 78474953.6491, 78892755.8494, 79310622.1206, 79728670.8983, 80146430.1861, 80564676.6574, 80982839.2219, 81400875.8007, 81818629.6193, 82236723.1996, 82655266.3646, 83073778.4807, 83491516.3923, 83909735.1914},
+}
+
+var simulatedBiasData = [][]float64{
+	// precision 19
+	{378167.627, 371401.2417, 364710.7521, 358094.2993, 351553.5938, 345089.0185, 338703.7098, 332397.3536, 326153.6121, 319992.7708, 313899.6374, 307892.0388, 301952.5087, 296092.829, 290310.5686, 284600.3553, 278953.5529, 273396.9373, 267916.8969, 262506.4303, 257178.1994, 251921.1668, 246727.5598, 241614.1327, 236579.6049, 231606.9884, 226722.0363, 221906.5845, 217161.2918, 212490.2501, 207896.9431, 203369.8291, 198901.7142, 194532.7191, 190234.8527, 185989.5246, 181796.919, 177701.9269, 173644.9274, 169694.9899, 165796.8275, 161963.633, 158174.8451, 154463.4725, 150826.7875, 147262.5852, 143724.9734, 140292.9737, 136913.1735, 133575.92, 130329.6625, 127128.7672, 123994.7749, 120929.8203, 117894.2362, 114951.5237, 112056.1261, 109219.2363, 106426.0743, 103698.1228, 101025.2944, 98415.2168, 95868.5983, 93352.7927, 90892.7866, 88460.0022, 86099.9775, 83774.0855, 81507.3501, 79321.869, 77172.5803, 75070.2408, 73000.3566, 70958.0787, 68984.2634, 67106.7316, 65183.1842, 63322.1971, 61540.2338, 59774.0578, 58064.4029, 56422.733, 54806.231, 53212.7636, 51686.8351, 50121.5546, 48610.8099, 47170.983, 45783.5922, 44370.0715, 43011.1819, 41748.1025, 40486.6074, 39249.5227, 38048.5919, 36915.4059, 35752.9296, 34670.5863, 33632.9407, 32653.0339, 31639.8273, 30622.2904, 29665.6879, 28734.9551, 27772.4395, 26909.8149, 26049.1104, 25193.2949, 24389.5452, 23575.2911, 22854.6597, 22082.1694, 21377.0838, 20649.9984, 19958.0482, 19346.9569, 18747.3025, 18097.4902, 17531.593, 16981.4791, 16419.1569, 15817.1027, 15255.2086, 14734.826, 14210.1262, 13700.7277, 13240.9871, 12759.4623, 12252.3697, 11786.886, 11392.6629, 11042.9359, 10684.1795, 10316.3655, 9902.9191, 9515.0256, 9243.279, 8913.0908, 8646.3421, 8303.7159, 7981.0598, 7672.4455, 7446.6401, 7175.5012, 6900.849, 6614.4618, 6314.9183, 6135.1612, 5976.2427, 5693.3011, 5463.1932, 5246.4313, 5088.6802, 4930.7775, 4773.6812, 4533.2294, 4419.8577, 4312.1006, 4191.4291, 4113.9576, 3961.8183, 3816.6272, 3601.4376, 3455.8806, 3299.9179, 3155.5177, 3059.3222, 2953.7414, 2833.2497, 2739.5156, 2653.0885, 2597.6981, 2550.0814, 2515.4045, 2393.262, 2375.7463, 2295.0815, 2273.6794, 2194.6841, 2124.88, 2065.5215, 1992.7583, 2005.9347, 1971.7633, 1872.7782, 1796.6558, 1715.433, 1645.9298, 1697.9007, 1575.3236, 1518.2367, 1524.0899, 1489.5156, 1454.4438, 1396.2156, 1341.9454, 1316.384, 1217.3293, 1271.1789, 1182.0319, 1157.1212},
+	// precision 20
+	{756336.554, 742802.9952, 729419.0035, 716191.1536, 703109.7231, 690183.5978, 677402.7433, 664782.6497, 652299.017, 639982.6744, 627807.557, 615789.6146, 603915.0276, 592200.5951, 580639.3338, 569224.1627, 557948.9928, 546833.4961, 535869.54, 525042.9887, 514376.2637, 503861.3589, 493488.2584, 483255.4446, 473183.5513, 463262.2741, 453487.3065, 443859.3922, 434381.897, 425032.5173, 415837.3918, 406796.2717, 397854.2964, 389090.1315, 380417.6773, 371944.1928, 363600.679, 355384.1494, 347295.8205, 339370.0349, 331568.0565, 323895.1069, 316353.203, 308907.8128, 301654.9302, 294487.408, 287477.189, 280597.1203, 273826.4849, 267233.0128, 260711.5874, 254332.2748, 248054.273, 241943.3364, 235920.7978, 230013.4948, 224214.2872, 218517.2307, 212941.5933, 207505.355, 202219.6185, 196971.8437, 191842.7553, 186817.0991, 181858.8223, 177049.6868, 172397.7991, 167813.9119, 163329.3029, 158944.1015, 154610.6086, 150445.1707, 146326.3105, 142220.9693, 138330.784, 134473.3368, 130736.5421, 127095.8729, 123520.2161, 120076.8433, 116663.8628, 113273.9077, 110044.5198, 106864.6564, 103771.4924, 100736.5636, 97843.8629, 94934.8142, 92157.1473, 89394.6806, 86736.3446, 84137.5173, 81622.9339, 79191.2812, 76809.8321, 74458.2298, 72202.0363, 69967.7949, 67790.2863, 65703.7273, 63643.1227, 61618.6152, 59681.8636, 57819.2008, 55940.8646, 54214.0307, 52465.6088, 50757.9435, 49060.6179, 47406.7019, 45909.003, 44499.5811, 43051.7661, 41678.717, 40357.1542, 39019.7174, 37647.596, 36433.4465, 35158.3579, 33958.4637, 32832.4743, 31677.1124, 30538.4553, 29459.6316, 28477.0244, 27509.7173, 26525.8232, 25592.1474, 24736.2734, 23854.2624, 23016.2626, 22242.5042, 21463.8928, 20649.4496, 19952.0428, 19250.1763, 18520.1461, 17886.5387, 17204.9611, 16577.7593, 15937.751, 15235.3939, 14708.7681, 14253.6448, 13731.8378, 13270.2028, 12752.8347, 12319.9171, 11705.9768, 11239.3656, 10740.73, 10283.3595, 9940.5984, 9492.9011, 9181.5538, 8789.9685, 8359.7349, 8079.0772, 7668.51, 7219.8334, 6839.6985, 6443.7164, 6150.8805, 5931.4642, 5557.6499, 5321.8119, 5058.5037, 4793.4773, 4571.3874, 4406.3384, 4282.71, 3974.4412, 3724.6558, 3604.0925, 3406.2494, 3223.8683, 3157.1135, 2978.8674, 2803.5711, 2697.4666, 2567.3121, 2423.6471, 2296.0212, 2251.6575, 2224.5166, 2171.7355, 2018.2456, 1993.9173, 1932.2542, 1904.4648, 1765.2754, 1673.1458, 1606.812, 1498.8073, 1282.6393, 1350.2132, 1305.0279, 1199.0608, 1169.1768, 1065.8467, 982.1595},
+	// precision 21
+	{1512674.4543, 1485610.0808, 1458843.5337, 1432382.2187, 1406220.7733, 1380357.0561, 1354801.0485, 1329553.3673, 1304606.0398, 1279946.8154, 1255587.1924, 1231547.2286, 1207807.7959, 1184373.4108, 1161252.9692, 1138397.7683, 1115849.5076, 1093588.0242, 1071682.3606, 1050055.1687, 1028735.7417, 1007706.5958, 986961.5252, 966524.5109, 946381.4594, 926560.1194, 906974.1814, 887731.9533, 868736.2391, 850010.0533, 831635.9842, 813528.0196, 795654.4716, 778132.5085, 760886.9178, 743927.5618, 727246.6625, 710787.9672, 694662.4152, 678758.4407, 663123.9151, 647766.9571, 632720.1515, 617863.5648, 603275.7888, 588964.5492, 574938.5458, 561139.9144, 547590.426, 534298.3483, 521261.318, 508480.462, 495946.6337, 483637.064, 471511.1069, 459669.5212, 448113.2513, 436760.0801, 425664.882, 414728.281, 404048.8233, 393511.9707, 383221.3347, 373160.9155, 363338.635, 353742.3081, 344392.792, 335233.5348, 326229.4376, 317409.7648, 308806.3114, 300379.22, 292174.7748, 284134.3427, 276266.3619, 268610.8283, 261074.27, 253786.509, 246567.8192, 239547.1012, 232569.2404, 225920.458, 219412.2378, 212979.1172, 206819.4787, 200798.9442, 194823.8865, 189127.5462, 183575.4771, 178116.1631, 172777.8923, 167664.4203, 162653.5434, 157698.5274, 152895.089, 148236.1652, 143666.417, 139178.5885, 134843.5801, 130643.6621, 126509.2848, 122449.6551, 118625.6249, 114805.3908, 111202.1849, 107605.5238, 104142.6233, 100842.4704, 97585.0328, 94401.9963, 91445.5537, 88514.6492, 85625.4701, 82879.0306, 80164.9454, 77467.9923, 74932.1914, 72530.7271, 70040.4184, 67682.1711, 65310.5408, 63037.7637, 60961.7558, 58880.6753, 56937.1168, 54983.172, 53036.9981, 51172.4057, 49372.449, 47621.3012, 46010.562, 44306.1143, 42745.8083, 41208.3159, 39639.8749, 38126.5691, 36676.8297, 35492.6792, 34206.246, 33115.691, 31939.1789, 30827.2147, 29741.7314, 28730.1301, 27539.7489, 26465.8047, 25402.6109, 24542.2937, 23624.3703, 22665.7788, 21724.5408, 20954.6255, 20236.9668, 19423.9538, 18699.3892, 17801.9551, 17224.4847, 16421.0747, 15821.4655, 15327.4881, 14712.6332, 14250.387, 13648.0414, 13239.5445, 12687.2823, 12145.691, 11526.5433, 11071.0555, 10576.7796, 10253.8645, 9827.9367, 9389.6291, 8907.9678, 8628.6288, 8203.2184, 7879.5872, 7606.9951, 7555.2633, 7248.3308, 6896.8022, 6761.7297, 6426.8972, 6152.5514, 5894.2358, 5694.3967, 5328.0607, 5303.9466, 5035.0614, 4910.7381, 4722.2164, 4368.8333, 4083.9357, 4128.6037, 3789.5322, 3480.3618, 3427.0145, 3354.1997, 3033.4375, 2897.8188, 2607.1489, 2421.9686},
+	// precision 22
+	{3025350.1924, 2971216.5825, 2917680.1723, 2864742.6285, 2812427.5256, 2760690.1395, 2709591.1256, 2659091.9331, 2609174.2975, 2559869.5421, 2511173.3667, 2463090.7374, 2415614.6263, 2368727.801, 2322454.1593, 2276778.1535, 2231706.0977, 2187246.4008, 2143398.6534, 2100127.024, 2057446.1207, 2015360.416, 1973885.0891, 1933005.4903, 1892723.8119, 1852994.2489, 1813871.639, 1775296.2123, 1737347.7847, 1699962.9857, 1663133.7337, 1626862.9444, 1591206.0492, 1556116.3106, 1521603.0008, 1487629.0349, 1454215.7891, 1421383.4172, 1389067.3153, 1357329.9466, 1326072.8511, 1295381.8747, 1265128.1138, 1235468.7763, 1206408.5054, 1177869.2601, 1149804.7028, 1122279.6027, 1095316.671, 1068747.8114, 1042660.1065, 1017119.0468, 992054.7679, 967466.4903, 943353.7632, 919789.5116, 896624.2642, 873890.6554, 851602.3421, 829808.5774, 808386.2304, 787454.673, 766953.0627, 746884.5531, 727164.1167, 707850.5195, 689005.7163, 670522.2642, 652433.1391, 634797.2048, 617603.4544, 600712.1942, 584202.4981, 568080.8332, 552363.8867, 536917.7575, 521973.0273, 507297.5767, 492948.3291, 478899.4, 465297.3402, 452023.1528, 439126.5504, 426409.6003, 413988.7808, 401899.7033, 390046.0001, 378582.0259, 367443.3303, 356677.2426, 346146.2347, 335782.4558, 325767.2087, 315943.5801, 306346.8014, 296904.2476, 287852.8727, 278924.6523, 270434.1412, 261976.6453, 253676.4239, 245690.5996, 237853.3266, 230425.0181, 223229.5804, 216129.4064, 209327.4046, 202587.1031, 195904.3968, 189603.2105, 183405.6145, 177401.1131, 171615.9594, 165921.7781, 160627.6418, 155363.3742, 150319.7192, 145369.7168, 140417.7964, 135748.8885, 131181.223, 126774.64, 122537.4382, 118309.9686, 114270.1935, 110181.5496, 106363.8701, 102611.5998, 99081.2783, 95467.0387, 92153.7662, 88998.8189, 85945.6922, 82887.2086, 79920.9482, 77060.3178, 74371.1433, 71722.4435, 69273.0839, 66592.4817, 64215.1194, 61893.9549, 59598.6912, 57304.0531, 55154.3859, 53327.3333, 51252.9687, 49396.4137, 47640.2759, 45956.7464, 44400.7712, 42744.8767, 40992.9927, 39517.6138, 37998.1938, 36447.2902, 35059.704, 33697.1807, 32348.337, 31212.8735, 30093.8001, 28980.1831, 27891.6598, 26632.2586, 25768.3211, 24961.7843, 23792.8428, 22741.6318, 21772.9637, 21202.9619, 20505.5146, 19492.8195, 18772.7608, 17940.8526, 17331.5644, 16953.794, 16322.4251, 15498.354, 14958.9516, 14342.8015, 13631.16, 13325.0238, 12964.9985, 12244.5822, 11928.4701, 11663.3147, 11005.0178, 10829.0319, 10389.0649, 9878.4953, 9356.0742, 9134.0462, 8666.6488, 8311.5843, 8004.7004, 7419.1669, 7111.6736, 6662.6883, 6361.0559, 6082.4885, 5663.6293},
+	// precision 23
+	{6050701.6482, 5942423.6371, 5835352.383, 5729494.2891, 5624840.0091, 5521381.4575, 5419151.0626, 5318112.102, 5218314.3703, 5119732.6801, 5022343.2813, 4926164.8273, 4831186.2757, 4737404.1798, 4644848.7884, 4553494.7815, 4463345.5713, 4374424.62, 4286672.8424, 4200143.1109, 4114821.9026, 4030601.223, 3947613.2922, 3865827.4891, 3785257.3345, 3705846.4495, 3627659.9277, 3550559.2141, 3474630.5858, 3399839.2119, 3326196.3819, 3253729.2746, 3182399.4565, 3112140.7598, 3043044.9358, 2975086.2345, 2908234.0462, 2842534.3058, 2777967.8446, 2714477.111, 2651959.29, 2590619.9117, 2530315.2729, 2471056.9296, 2412890.5417, 2355806.4256, 2299805.9741, 2244654.9884, 2190515.1229, 2137430.6591, 2085350.1036, 2034165.6753, 1984130.7633, 1934970.5594, 1886580.0121, 1839226.2121, 1792835.6963, 1747428.736, 1703036.1857, 1659394.2674, 1616543.6263, 1574712.1446, 1533627.7804, 1493358.0182, 1454025.8329, 1415644.0106, 1377994.7321, 1341134.9977, 1305221.1309, 1269922.1878, 1235437.4735, 1201768.5771, 1168715.6767, 1136657.52, 1105298.9187, 1074593.9143, 1044596.7859, 1015300.2393, 986607.9874, 958572.2187, 931209.0107, 904649.6718, 878558.1783, 853331.8759, 828424.2086, 804387.5482, 780767.6543, 757669.4582, 735449.8261, 713530.8785, 692249.6545, 671528.1171, 651484.2337, 631732.1735, 612609.9056, 593905.6976, 575752.3777, 557987.0129, 540662.0372, 523952.0471, 507823.7918, 491730.3371, 476112.3275, 461157.9103, 446268.8447, 432085.6088, 418187.9233, 404683.7786, 391904.2452, 379240.7612, 366798.4077, 354659.1984, 343220.8604, 331749.908, 320881.1059, 310221.6052, 299737.1452, 289703.7491, 279957.6086, 270696.3475, 261848.703, 252869.3606, 244174.7587, 235828.2953, 227772.7338, 219878.2118, 212228.5709, 204770.3748, 197553.9252, 190567.609, 183856.3028, 177461.3965, 171267.4106, 165168.8457, 159759.7667, 154271.0061, 148817.685, 143246.9953, 138249.1875, 133300.2918, 128706.5371, 123750.4218, 119427.8117, 115279.6875, 111326.7806, 107318.6799, 103158.6416, 99323.116, 95791.8987, 92222.7525, 88581.7407, 85293.5265, 81775.3065, 78592.7236, 75725.5223, 72971.2159, 70058.7845, 67248.6291, 64641.349, 61985.2936, 59664.7926, 57355.5656, 55119.1868, 53253.5815, 51258.9584, 49373.8355, 47054.3375, 45247.2473, 43580.7041, 42125.4639, 40572.3626, 39025.6907, 37631.0681, 36037.4043, 34428.0523, 32951.9545, 31822.617, 30130.6169, 28946.944, 27758.147, 26530.2814, 25527.9677, 24505.8109, 23682.8007, 22768.7639, 21949.6202, 21031.2032, 20093.2479, 19346.4831, 18380.1327, 17403.9586, 16911.472, 16364.4081, 15724.9983, 15092.7201, 14457.1734, 13660.6683, 13067.6477, 12474.8251, 11718.14, 11400.498},
+	// precision 24
+	{12101404.595, 11884838.423, 11670682.0116, 11458937.8114, 11249635.7763, 11042739.3628, 10838257.6771, 10636192.6378, 10436546.2312, 10239329.6704, 10044559.1204, 9852165.2154, 9662221.0339, 9474684.4147, 9289564.8408, 9106832.4802, 8926570.6376, 8748663.1494, 8573186.5691, 8400150.0773, 8229420.6176, 8061177.1159, 7895256.0151, 7731783.6725, 7570540.8806, 7411677.3672, 7255110.4088, 7100936.8029, 6949126.6273, 6799696.944, 6652458.2495, 6507502.1095, 6364794.4078, 6224423.6406, 6086261.2414, 5950319.5498, 5816544.7347, 5685160.0488, 5555979.2513, 5428870.1566, 5303831.3686, 5181036.2375, 5060339.5852, 4941829.6194, 4825368.9706, 4711015.9146, 4598845.2418, 4488614.4559, 4380392.8668, 4274229.4471, 4170125.3573, 4067628.1739, 3967395.2231, 3869084.1766, 3772635.2241, 3678075.8648, 3585381.4431, 3494460.906, 3405351.664, 3318126.8594, 3232709.0572, 3148861.4227, 3067030.927, 2986798.9432, 2908092.7547, 2831094.8977, 2756051.6353, 2682529.4764, 2610371.6041, 2539942.9252, 2470957.4777, 2403515.3547, 2337755.1012, 2273441.3636, 2210390.1875, 2148750.2422, 2088537.854, 2029773.8386, 1972559.4107, 1916658.5305, 1862025.442, 1808885.2858, 1757062.2994, 1706194.4343, 1656751.1934, 1608471.4658, 1561578.9091, 1515567.7662, 1470840.1648, 1427150.7198, 1384656.3838, 1343262.0099, 1302784.5405, 1263561.8675, 1225164.0881, 1187534.5592, 1151340.2362, 1116126.5154, 1081637.7905, 1048145.4178, 1015556.619, 984032.2246, 953028.5484, 922891.0259, 893886.2447, 865657.9154, 838022.6026, 811274.6592, 785227.4739, 759918.4334, 735603.2877, 711837.691, 688877.0425, 666264.3877, 643996.9952, 622359.7246, 601847.8213, 581637.8969, 562522.3975, 543555.8389, 525071.661, 507511.3783, 490099.4898, 473780.9737, 457523.2079, 441695.2955, 426477.1171, 412219.018, 397850.6942, 384236.1322, 370840.5174, 358165.4576, 345792.1231, 333876.9319, 322137.6645, 311067.4732, 300172.5212, 290084.5573, 279699.9775, 269823.8578, 260109.1817, 250688.0221, 241459.7551, 232602.3725, 224165.0009, 216069.355, 207902.0852, 200276.2245, 192982.0052, 185966.0235, 178865.2438, 172036.2832, 165872.8348, 159593.6683, 153797.7277, 147490.2821, 141826.1031, 136914.0465, 132149.655, 126927.091, 122107.1082, 117147.8644, 112554.9365, 108115.6974, 103793.2867, 99864.6865, 95951.4299, 92131.4291, 88802.9775, 85269.2964, 82455.5272, 79289.1269, 76581.6722, 73764.5456, 71055.7246, 68318.8492, 65856.8071, 63319.117, 60667.0132, 58169.7374, 55537.0457, 53274.2906, 51073.5523, 49085.1007, 47014.5336, 45019.5588, 43225.8557, 41542.6491, 39914.8494, 38351.1206, 36969.8983, 35299.1861, 34115.6574, 32848.2219, 31454.8007, 29778.6193, 28442.1996, 27555.3646, 26637.4807, 24945.3923, 23734.1914},
+}
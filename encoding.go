@@ -0,0 +1,424 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// The binary encoding starts with a version byte, a kind byte and the
+// precision, followed by the representation. Dense kinds store one byte per
+// register. The sparse kind stores the length of the sparse list in bytes as a
+// uvarint followed by the list itself. HyperMinHash stores two bytes per
+// register, little endian. KMV sketches have no precision, so their kind byte
+// is followed directly by k, the number of hashes and the hashes in ascending
+// order, as uvarint differences from the previous hash.
+const encodingVersion = 1
+
+const (
+	kindHLL = iota + 1
+	kindHLL64
+	kindPlusNormal
+	kindPlusSparse
+	kindMinHash
+	kindKMV
+)
+
+var errEncodingVersion = errors.New("unsupported encoding version")
+var errEncodingKind = errors.New("encoded sketch has the wrong kind")
+
+// Counts the bytes read from r, and reads single bytes without reading ahead so
+// that decoding a sketch never consumes bytes past its end.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	buf [1]byte
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(c, c.buf[:]); err != nil {
+		return 0, err
+	}
+	return c.buf[0], nil
+}
+
+// Writes the header of an encoded sketch, followed by the uvarint length of
+// the payload for sparse sketches, followed by the payload.
+func writeSketch(w io.Writer, kind, p uint8, payload []byte) (int64, error) {
+	hdr := make([]byte, 3, 3+binary.MaxVarintLen32)
+	hdr[0], hdr[1], hdr[2] = encodingVersion, kind, p
+	if kind == kindPlusSparse {
+		hdr = binary.AppendUvarint(hdr, uint64(len(payload)))
+	}
+
+	n, err := w.Write(hdr)
+	if err != nil {
+		return int64(n), err
+	}
+	n2, err := w.Write(payload)
+	return int64(n + n2), err
+}
+
+// Reads the header of an encoded sketch, returning its kind and precision, or
+// a precision of 0 for a KMV sketch. It returns io.EOF if r is at its end, and
+// io.ErrUnexpectedEOF if r ends part way through the header.
+func readHeader(r *countingReader) (uint8, uint8, error) {
+	var hdr [3]byte
+	if _, err := io.ReadFull(r, hdr[:2]); err != nil {
+		return 0, 0, err
+	}
+	if hdr[0] != encodingVersion {
+		return 0, 0, errEncodingVersion
+	}
+	if hdr[1] == kindKMV {
+		return kindKMV, 0, nil
+	}
+	if _, err := io.ReadFull(r, hdr[2:]); err != nil {
+		return 0, 0, unexpectedEOF(err)
+	}
+	if hdr[2] < 4 || hdr[2] > pPrime-1 {
+		return 0, 0, errors.New("invalid precision")
+	}
+	return hdr[1], hdr[2], nil
+}
+
+// Reads the m registers of an encoded sketch.
+func readRegisters(r *countingReader, m uint32) ([]uint8, error) {
+	reg := make([]uint8, m)
+	if _, err := io.ReadFull(r, reg); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return reg, nil
+}
+
+// Reads a uvarint, requiring it to be encoded in as few bytes as possible.
+func readUvarint(r *countingReader) (uint64, error) {
+	start := r.n
+	x, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if r.n-start != int64(len(binary.AppendUvarint(nil, x))) {
+		return 0, errors.New("invalid uvarint")
+	}
+	return x, nil
+}
+
+// Reads the length and sparse list of an encoded sketch.
+func readSparseList(r *countingReader, m uint32) (*compressedList, error) {
+	n, err := readUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(m) {
+		return nil, errors.New("sparse list is too long")
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return decodeSparseList(b)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Decodes a sketch with decode, requiring it to use all of b.
+func unmarshalBinary(b []byte, decode func(io.Reader) (int64, error)) error {
+	r := bytes.NewReader(b)
+	if _, err := decode(r); err != nil {
+		return unexpectedEOF(err)
+	}
+	if r.Len() != 0 {
+		return errors.New("trailing data after encoded sketch")
+	}
+	return nil
+}
+
+// WriteTo writes HyperLogLog h to w in the format of MarshalBinary, without
+// building the encoding in memory first.
+func (h *HyperLogLog) WriteTo(w io.Writer) (int64, error) {
+	kind := uint8(kindHLL)
+	if h.wide {
+		kind = kindHLL64
+	}
+	return writeSketch(w, kind, h.p, h.reg)
+}
+
+// ReadFrom reads a HyperLogLog written by WriteTo or MarshalBinary from r into
+// h. It reads exactly one sketch and never reads past its end, so several
+// sketches can be read one after another from the same reader. It returns
+// io.EOF if r is already at its end.
+func (h *HyperLogLog) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	kind, p, err := readHeader(cr)
+	if err != nil {
+		return cr.n, err
+	}
+	if kind != kindHLL && kind != kindHLL64 {
+		return cr.n, errEncodingKind
+	}
+
+	if p > 16 {
+		return cr.n, errors.New("precision must be between 4 and 16")
+	}
+
+	g := HyperLogLog{p: p, m: 1 << p, wide: kind == kindHLL64}
+	if g.reg, err = readRegisters(cr, g.m); err != nil {
+		return cr.n, err
+	}
+	if err := g.validate(); err != nil {
+		return cr.n, err
+	}
+	*h = g
+	return cr.n, nil
+}
+
+// MarshalBinary encodes HyperLogLog h in a stable binary format.
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 3+len(h.reg)))
+	if _, err := h.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a HyperLogLog encoded with MarshalBinary into h.
+func (h *HyperLogLog) UnmarshalBinary(b []byte) error {
+	return unmarshalBinary(b, h.ReadFrom)
+}
+
+// WriteTo writes HyperLogLogPlus h to w in the format of MarshalBinary. The
+// registers of a normal sketch are written without copying them.
+func (h *HyperLogLogPlus) WriteTo(w io.Writer) (int64, error) {
+	if h.sparse {
+		l := h.mergedList()
+		if uint32(l.Len()) <= h.m {
+			return writeSketch(w, kindPlusSparse, h.p, l.b)
+		}
+	}
+	return writeSketch(w, kindPlusNormal, h.p, h.registers())
+}
+
+// ReadFrom reads a HyperLogLogPlus written by WriteTo or MarshalBinary from r
+// into h. It reads exactly one sketch and never reads past its end, so several
+// sketches can be read one after another from the same reader. It returns
+// io.EOF if r is already at its end.
+func (h *HyperLogLogPlus) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	kind, p, err := readHeader(cr)
+	if err != nil {
+		return cr.n, err
+	}
+
+	g := HyperLogLogPlus{p: p, m: 1 << p}
+	switch kind {
+	case kindPlusNormal:
+		g.reg, err = readRegisters(cr, g.m)
+	case kindPlusSparse:
+		g.sparse = true
+		g.tmpSet = set{}
+		g.sparseList, err = readSparseList(cr, g.m)
+	default:
+		err = errEncodingKind
+	}
+	if err != nil {
+		return cr.n, err
+	}
+
+	if err := g.validate(); err != nil {
+		return cr.n, err
+	}
+	*h = g
+	return cr.n, nil
+}
+
+// MarshalBinary encodes HyperLogLogPlus h in a stable binary format. The
+// temporary set of a sparse sketch is merged into the encoded sparse list, but
+// h itself is not modified.
+func (h *HyperLogLogPlus) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := h.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a HyperLogLogPlus encoded with MarshalBinary into h.
+func (h *HyperLogLogPlus) UnmarshalBinary(b []byte) error {
+	return unmarshalBinary(b, h.ReadFrom)
+}
+
+// Decodes an encoded sparse list, requiring it to be strictly increasing and
+// encoded exactly as compressedList would encode it.
+func decodeSparseList(b []byte) (*compressedList, error) {
+	src := &compressedList{b: b}
+	l := newCompressedList(len(b))
+	for iter := src.Iter(); iter.HasNext(); {
+		x := iter.Next()
+		if l.Count > 0 && x <= l.last {
+			return nil, errors.New("invalid sparse list")
+		}
+		l.Append(x)
+	}
+	if !bytes.Equal(l.b, b) {
+		return nil, errors.New("invalid sparse list")
+	}
+	return l, nil
+}
+
+// The JSON encoding wraps the binary encoding in an envelope describing the
+// sketch. The estimate is informational and ignored when decoding.
+type jsonSketch struct {
+	Algorithm      string `json:"algorithm"`
+	Precision      uint8  `json:"precision"`
+	Representation string `json:"representation"`
+	Estimate       uint64 `json:"estimate"`
+	Data           []byte `json:"data"`
+}
+
+var errJSONKind = errors.New("encoded sketch is not a HyperLogLog or HyperLogLog++")
+
+// Returns the algorithm and representation names for an encoded sketch. Only
+// HyperLogLog and HyperLogLog++ sketches have a JSON encoding.
+func describeEncoding(b []byte) (string, string, error) {
+	switch b[1] {
+	case kindHLL:
+		return "HyperLogLog", "normal", nil
+	case kindHLL64:
+		return "HyperLogLog64", "normal", nil
+	case kindPlusNormal:
+		return "HyperLogLog++", "normal", nil
+	case kindPlusSparse:
+		return "HyperLogLog++", "sparse", nil
+	}
+	return "", "", errJSONKind
+}
+
+func marshalJSON(b []byte, estimate uint64) ([]byte, error) {
+	alg, rep, err := describeEncoding(b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonSketch{
+		Algorithm:      alg,
+		Precision:      b[2],
+		Representation: rep,
+		Estimate:       estimate,
+		Data:           b,
+	})
+}
+
+// Returns the binary encoding from a JSON envelope, checking that the
+// envelope describes it correctly.
+func unmarshalJSON(data []byte) ([]byte, error) {
+	var s jsonSketch
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Data) < 3 {
+		return nil, errors.New("encoded sketch is too short")
+	}
+	alg, rep, err := describeEncoding(s.Data)
+	if err != nil {
+		return nil, err
+	}
+	if s.Algorithm != alg || s.Precision != s.Data[2] || s.Representation != rep {
+		return nil, errors.New("sketch description does not match data")
+	}
+	return s.Data, nil
+}
+
+// MarshalJSON encodes HyperLogLog h as a JSON object holding its algorithm,
+// precision, representation, estimate and base64 encoded binary encoding.
+func (h *HyperLogLog) MarshalJSON() ([]byte, error) {
+	b, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return marshalJSON(b, h.Count())
+}
+
+// UnmarshalJSON decodes a HyperLogLog encoded with MarshalJSON into h.
+func (h *HyperLogLog) UnmarshalJSON(data []byte) error {
+	b, err := unmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+	return h.UnmarshalBinary(b)
+}
+
+// MarshalText encodes HyperLogLog h as the base64 encoding of MarshalBinary.
+func (h *HyperLogLog) MarshalText() ([]byte, error) {
+	b, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	text := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+	base64.StdEncoding.Encode(text, b)
+	return text, nil
+}
+
+// UnmarshalText decodes a HyperLogLog encoded with MarshalText into h.
+func (h *HyperLogLog) UnmarshalText(text []byte) error {
+	b, err := base64.StdEncoding.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	return h.UnmarshalBinary(b)
+}
+
+// MarshalJSON encodes HyperLogLogPlus h as a JSON object holding its
+// algorithm, precision, representation, estimate and base64 encoded binary
+// encoding.
+func (h *HyperLogLogPlus) MarshalJSON() ([]byte, error) {
+	b, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return marshalJSON(b, h.Count())
+}
+
+// UnmarshalJSON decodes a HyperLogLogPlus encoded with MarshalJSON into h.
+func (h *HyperLogLogPlus) UnmarshalJSON(data []byte) error {
+	b, err := unmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+	return h.UnmarshalBinary(b)
+}
+
+// MarshalText encodes HyperLogLogPlus h as the base64 encoding of
+// MarshalBinary.
+func (h *HyperLogLogPlus) MarshalText() ([]byte, error) {
+	b, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	text := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+	base64.StdEncoding.Encode(text, b)
+	return text, nil
+}
+
+// UnmarshalText decodes a HyperLogLogPlus encoded with MarshalText into h.
+func (h *HyperLogLogPlus) UnmarshalText(text []byte) error {
+	b, err := base64.StdEncoding.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	return h.UnmarshalBinary(b)
+}
@@ -0,0 +1,311 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestHLLMarshalBinary(t *testing.T) {
+	h, _ := New(8)
+	h.Add(fakeHash32(0x00010fff))
+	h64, _ := New64(8)
+	h64.Add64(fakeHash64(0x10fffffffffff))
+
+	for _, h := range []*HyperLogLog{h, h64} {
+		b, err := h.MarshalBinary()
+		if err != nil {
+			t.Error(err)
+		}
+		if len(b) != 3+int(h.m) {
+			t.Error(len(b))
+		}
+
+		var h2 HyperLogLog
+		if err := h2.UnmarshalBinary(b); err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(h, &h2) {
+			t.Error("unmarshaled structure differs")
+		}
+	}
+}
+
+func TestHLLPPMarshalBinary(t *testing.T) {
+	h, _ := NewPlus(10)
+	for i := 0; i < 50; i++ {
+		h.Add(fakeHash64(uint64(i) * 0x9e3779b97f4a7c15))
+	}
+	h.Flush()
+	h.Add(fakeHash64(0xdeadbeef00000000))
+
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Error(err)
+	}
+	if b[1] != kindPlusSparse {
+		t.Error("expected sparse encoding")
+	}
+	if len(h.tmpSet) != 1 {
+		t.Error("MarshalBinary modified sketch")
+	}
+
+	var h2 HyperLogLogPlus
+	if err := h2.UnmarshalBinary(b); err != nil {
+		t.Error(err)
+	}
+	if !h2.sparse || !h.Equal(&h2) || h.Count() != h2.Count() {
+		t.Error("unmarshaled sketch differs")
+	}
+
+	for i := 0; i < 5000; i++ {
+		h.Add(fakeHash64(uint64(i+1000) * 0x9e3779b97f4a7c15))
+	}
+	b, err = h.MarshalBinary()
+	if err != nil {
+		t.Error(err)
+	}
+	if b[1] != kindPlusNormal || len(b) != 3+int(h.m) {
+		t.Error("expected normal encoding")
+	}
+
+	var h3 HyperLogLogPlus
+	if err := h3.UnmarshalBinary(b); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(h, &h3) {
+		t.Error("unmarshaled structure differs")
+	}
+}
+
+func TestHLLPPMarshalBinaryStable(t *testing.T) {
+	h, _ := NewPlus(4)
+	h.Add(fakeHash64(0x0001000000100000))
+	h.Add(fakeHash64(0xf000000000000001))
+
+	b, _ := h.MarshalBinary()
+	want := []byte{1, 4, 4, 7, 0x80, 0x08, 0xcf, 0xf8, 0xff, 0xff, 0x0e}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("%#v", b)
+	}
+}
+
+func TestHLLUnmarshalBinaryError(t *testing.T) {
+	h, _ := New(4)
+	b, _ := h.MarshalBinary()
+
+	for _, b := range [][]byte{
+		nil,
+		{1, kindHLL},
+		{2, kindHLL, 4},
+		{1, kindPlusNormal, 4},
+		{1, kindHLL, 3, 0, 0, 0, 0, 0, 0, 0, 0},
+		{1, kindHLL, 40},
+		b[:len(b)-1],
+		append(b, 0),
+	} {
+		var h HyperLogLog
+		if err := h.UnmarshalBinary(b); err == nil {
+			t.Errorf("accepted %v", b)
+		}
+	}
+}
+
+func TestHLLPPUnmarshalBinaryError(t *testing.T) {
+	h, _ := NewPlus(4)
+	h.Add(fakeHash64(0x0001000000100000))
+	sparse, _ := h.MarshalBinary()
+	h.Flush()
+	h.toNormal()
+	normal, _ := h.MarshalBinary()
+
+	for _, b := range [][]byte{
+		nil,
+		{1, kindPlusNormal},
+		{2, kindPlusNormal, 4},
+		{1, kindHLL, 4},
+		{1, kindPlusNormal, 40},
+		normal[:len(normal)-1],
+		append(normal, 0),
+		{1, kindPlusSparse, 4},
+		{1, kindPlusSparse, 4, 2, 1},
+		{1, kindPlusSparse, 4, 2, 3, 0},
+		{1, kindPlusSparse, 4, 2, 0x83, 0},
+		{1, kindPlusSparse, 4, 17, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+		sparse[:len(sparse)-1],
+	} {
+		var h HyperLogLogPlus
+		if err := h.UnmarshalBinary(b); err == nil {
+			t.Errorf("accepted %v", b)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	h, _ := New64(8)
+	h.Add64(fakeHash64(0x10fffffffffff))
+	hpp, _ := NewPlus(10)
+	for i := 0; i < 50; i++ {
+		hpp.Add(fakeHash64(uint64(i) * 0x9e3779b97f4a7c15))
+	}
+
+	b, err := json.Marshal(struct {
+		H   *HyperLogLog
+		HPP *HyperLogLogPlus
+	}{h, hpp})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var envelopes struct{ H, HPP map[string]interface{} }
+	if err := json.Unmarshal(b, &envelopes); err != nil {
+		t.Fatal(err)
+	}
+	if e := envelopes.H; e["algorithm"] != "HyperLogLog64" || e["precision"] != 8.0 || e["representation"] != "normal" || e["estimate"] != float64(h.Count()) {
+		t.Error(e)
+	}
+	if e := envelopes.HPP; e["algorithm"] != "HyperLogLog++" || e["precision"] != 10.0 || e["representation"] != "sparse" || e["estimate"] != float64(hpp.Count()) {
+		t.Error(e)
+	}
+
+	var decoded struct {
+		H   HyperLogLog
+		HPP HyperLogLogPlus
+	}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h, &decoded.H) {
+		t.Error("unmarshaled HyperLogLog differs")
+	}
+	if !hpp.Equal(&decoded.HPP) {
+		t.Error("unmarshaled HyperLogLogPlus differs")
+	}
+
+	b2, _ := json.Marshal(struct {
+		H   *HyperLogLog
+		HPP *HyperLogLogPlus
+	}{&decoded.H, &decoded.HPP})
+	if !bytes.Equal(b, b2) {
+		t.Errorf("round trip changed %s to %s", b, b2)
+	}
+}
+
+func TestUnmarshalJSONError(t *testing.T) {
+	h, _ := NewPlus(4)
+	data := base64.StdEncoding.EncodeToString([]byte{1, kindPlusSparse, 4, 0})
+
+	for _, s := range []string{
+		`[]`,
+		`{"algorithm":"HyperLogLog++","precision":4,"representation":"sparse"}`,
+		`{"algorithm":"HyperLogLog++","precision":4,"representation":"sparse","data":"!"}`,
+		`{"algorithm":"HyperLogLog","precision":4,"representation":"sparse","data":"` + data + `"}`,
+		`{"algorithm":"HyperLogLog++","precision":5,"representation":"sparse","data":"` + data + `"}`,
+		`{"algorithm":"HyperLogLog++","precision":4,"representation":"normal","data":"` + data + `"}`,
+	} {
+		if err := h.UnmarshalJSON([]byte(s)); err == nil {
+			t.Errorf("accepted %s", s)
+		}
+	}
+
+	s := `{"algorithm":"HyperLogLog++","precision":4,"representation":"sparse","estimate":7,"data":"` + data + `"}`
+	if err := h.UnmarshalJSON([]byte(s)); err != nil {
+		t.Error(err)
+	}
+
+	for _, kind := range []byte{kindMinHash, kindKMV, 0, 99} {
+		data := base64.StdEncoding.EncodeToString([]byte{1, kind, 4, 0})
+		s := `{"algorithm":"HyperLogLog++","precision":4,"representation":"sparse","data":"` + data + `"}`
+		if err := h.UnmarshalJSON([]byte(s)); err != errJSONKind {
+			t.Errorf("kind %d: %v", kind, err)
+		}
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	h, _ := New(4)
+	h.Add(fakeHash32(0x00010fff))
+	hpp, _ := NewPlus(4)
+	hpp.Add(fakeHash64(0x0001000000100000))
+
+	text, err := h.MarshalText()
+	if err != nil {
+		t.Error(err)
+	}
+	var h2 HyperLogLog
+	if err := h2.UnmarshalText(text); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(h, &h2) {
+		t.Error("unmarshaled HyperLogLog differs")
+	}
+
+	text, err = hpp.MarshalText()
+	if err != nil {
+		t.Error(err)
+	}
+	if string(text) != "AQQEAoAI" {
+		t.Error(string(text))
+	}
+	var hpp2 HyperLogLogPlus
+	if err := hpp2.UnmarshalText(text); err != nil {
+		t.Error(err)
+	}
+	if !hpp.Equal(&hpp2) {
+		t.Error("unmarshaled HyperLogLogPlus differs")
+	}
+
+	if err := hpp2.UnmarshalText([]byte("not base64")); err == nil {
+		t.Error("accepted invalid text")
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	h, _ := New(4)
+	h.Add(fakeHash32(0x00010fff))
+	sparse, _ := NewPlus(10)
+	for i := 0; i < 50; i++ {
+		sparse.Add(fakeHash64(uint64(i) * 0x9e3779b97f4a7c15))
+	}
+	normal := sparse.Clone()
+	normal.Flush()
+	normal.toNormal()
+
+	var buf bytes.Buffer
+	var total int64
+	for _, w := range []io.WriterTo{h, sparse, normal} {
+		n, err := w.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += n
+	}
+	if total != int64(buf.Len()) {
+		t.Error(total, buf.Len())
+	}
+
+	var h2 HyperLogLog
+	var sparse2, normal2 HyperLogLogPlus
+	for _, r := range []io.ReaderFrom{&h2, &sparse2, &normal2} {
+		if _, err := r.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(h, &h2) || !sparse.Equal(&sparse2) || !reflect.DeepEqual(normal, &normal2) {
+		t.Error("read sketches differ")
+	}
+	if !sparse2.sparse {
+		t.Error("sparse sketch read as normal")
+	}
+
+	if _, err := sparse2.ReadFrom(&buf); err != io.EOF {
+		t.Error(err)
+	}
+	b, _ := sparse.MarshalBinary()
+	if _, err := sparse2.ReadFrom(bytes.NewReader(b[:len(b)-1])); err != io.ErrUnexpectedEOF {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,53 @@
+// Package expvarhll publishes HyperLogLog and HyperLogLog++ sketches through
+// expvar, so that /debug/vars shows the estimate, precision, representation
+// and memory use of each named sketch.
+//
+// A sketch that is written to while it is published must be guarded by a
+// lock, which is passed along with the sketch:
+//
+//	var mu sync.Mutex
+//	h, _ := hyperloglog.NewPlus(14)
+//	expvarhll.Publish("unique_clients", h, &mu)
+package expvarhll
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+// Sketch is implemented by HyperLogLog and HyperLogLogPlus.
+type Sketch interface {
+	Count() uint64
+	Info() hyperloglog.Info
+}
+
+// Stats is the value published for a sketch.
+type Stats struct {
+	Estimate uint64 `json:"estimate"`
+	hyperloglog.Info
+}
+
+// Read returns the current Stats of s, holding l while reading it if l is not
+// nil.
+func Read(s Sketch, l sync.Locker) Stats {
+	if l != nil {
+		l.Lock()
+		defer l.Unlock()
+	}
+	return Stats{s.Count(), s.Info()}
+}
+
+// Var returns an expvar.Var whose value is the current Stats of s, read while
+// holding l if l is not nil. It can be published directly or added to an
+// expvar.Map.
+func Var(s Sketch, l sync.Locker) expvar.Var {
+	return expvar.Func(func() interface{} { return Read(s, l) })
+}
+
+// Publish publishes s under name, reading it while holding l if l is not nil.
+// Like expvar.Publish, it panics if name is already registered.
+func Publish(name string, s Sketch, l sync.Locker) {
+	expvar.Publish(name, Var(s, l))
+}
@@ -0,0 +1,73 @@
+package expvarhll
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+type fakeHash64 uint64
+
+func (f fakeHash64) Sum64() uint64 { return uint64(f) }
+
+// Counts runs of TestPublish, since expvar names can only be published once
+// per process and tests may run several times with -count.
+var publishRuns int
+
+func TestPublish(t *testing.T) {
+	publishRuns++
+	name := fmt.Sprint("expvarhll_test_", publishRuns)
+
+	var mu sync.Mutex
+	h, _ := hyperloglog.NewPlus(10)
+	Publish(name, h, &mu)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			mu.Lock()
+			h.Add(fakeHash64(uint64(i+1) * 0x9e3779b97f4a7c15))
+			mu.Unlock()
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if !json.Valid([]byte(expvar.Get(name).String())) {
+			t.Error("invalid JSON")
+		}
+	}
+	wg.Wait()
+
+	var stats map[string]interface{}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats["estimate"] != float64(h.Count()) || stats["algorithm"] != "HyperLogLog++" || stats["precision"] != 10.0 || stats["representation"] != "normal" || stats["bytes"] != 1024.0 {
+		t.Error(stats)
+	}
+}
+
+func TestVarMap(t *testing.T) {
+	m := new(expvar.Map).Init()
+	hll, _ := hyperloglog.New(4)
+	hpp, _ := hyperloglog.NewPlus(4)
+	hpp.Add(fakeHash64(1))
+	m.Set("hll", Var(hll, nil))
+	m.Set("hpp", Var(hpp, nil))
+
+	var stats map[string]Stats
+	if err := json.Unmarshal([]byte(m.String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if s := stats["hll"]; s.Estimate != 0 || s.Algorithm != "HyperLogLog" || s.Registers != 16 {
+		t.Error(s)
+	}
+	if s := stats["hpp"]; s.Estimate != 1 || s.Representation != "sparse" || s.SparseEntries+s.TmpSetEntries != 1 {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,205 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func FuzzVariableLengthListDecode(f *testing.F) {
+	f.Add([]byte{151, 195, 6, 0x7f})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		l := variableLengthList(b)
+		for iter := l.Iter(); iter.HasNext(); {
+			iter.Peek()
+			iter.Next()
+		}
+	})
+}
+
+func FuzzCompressedList(f *testing.F) {
+	f.Add([]byte{0xff, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var xs []uint32
+		for ; len(b) >= 4; b = b[4:] {
+			xs = append(xs, binary.LittleEndian.Uint32(b))
+		}
+
+		l := newCompressedList(len(xs))
+		for _, x := range xs {
+			l.Append(x)
+		}
+		if int(l.Count) != len(xs) {
+			t.Fatal(l.Count, len(xs))
+		}
+
+		var got []uint32
+		for iter := l.Iter(); iter.HasNext(); {
+			got = append(got, iter.Next())
+		}
+		if len(xs) > 0 && !reflect.DeepEqual(got, xs) {
+			t.Fatalf("got %v, want %v", got, xs)
+		}
+	})
+}
+
+func FuzzEncodeHash(f *testing.F) {
+	f.Add(uint64(0xffffff8000000000), uint8(8))
+	f.Add(uint64(0xff00000000000000), uint8(8))
+	f.Add(uint64(0), uint8(4))
+	f.Add(uint64(1), uint8(24))
+	f.Fuzz(func(t *testing.T, x uint64, p uint8) {
+		h, err := NewPlus(4 + p%21)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The index and rank the normal representation would use.
+		idx := uint32(x >> (64 - h.p))
+		rank := clz64(x<<h.p|1<<(h.p-1)) + 1
+
+		i, r := h.decodeHash(h.encodeHash(x))
+		if i != idx || r != rank {
+			t.Fatalf("p=%d x=%#x: got (%d, %d), want (%d, %d)", h.p, x, i, r, idx, rank)
+		}
+	})
+}
+
+func FuzzHLLGobDecode(f *testing.F) {
+	h, _ := New(8)
+	h.Add(fakeHash32(0x10fff))
+	b, _ := h.GobEncode()
+	f.Add(b)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var h HyperLogLog
+		if err := h.GobDecode(b); err != nil {
+			return
+		}
+		h.Add(fakeHash32(0x10fff))
+		h.Add64(fakeHash64(0x10fffffffffff))
+		h.Count()
+		h.Merge(&h)
+	})
+}
+
+func FuzzHLLPPGobDecode(f *testing.F) {
+	h, _ := NewPlus(8)
+	h.Add(fakeHash64(0x10fff))
+	b, _ := h.GobEncode()
+	f.Add(b)
+	h.mergeSparseAndToNormal()
+	b, _ = h.GobEncode()
+	f.Add(b)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var h HyperLogLogPlus
+		if err := h.GobDecode(b); err != nil {
+			return
+		}
+		h2, _ := NewPlus(h.p)
+		h2.Add(fakeHash64(0x10fff))
+		h2.Merge(&h)
+		h.Merge(h2)
+		h.Add(fakeHash64(0x10fff))
+		h.Count()
+		h.Flush()
+	})
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	h, _ := NewPlus(8)
+	h.Add(fakeHash64(0x10fff))
+	b, _ := h.MarshalBinary()
+	f.Add(b)
+	h.Flush()
+	h.toNormal()
+	b, _ = h.MarshalBinary()
+	f.Add(b)
+	hll, _ := New(4)
+	b, _ = hll.MarshalBinary()
+	f.Add(b)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var h HyperLogLogPlus
+		if err := h.UnmarshalBinary(b); err == nil {
+			b2, _ := h.MarshalBinary()
+			if !bytes.Equal(b, b2) {
+				t.Errorf("round trip changed %v to %v", b, b2)
+			}
+			h.Add(fakeHash64(0x10fff))
+			h.Count()
+		}
+
+		var hll HyperLogLog
+		if err := hll.UnmarshalBinary(b); err == nil {
+			hll.Add(fakeHash32(0x10fff))
+			hll.Count()
+		}
+	})
+}
+
+// Builds a HyperLogLogPlus from n random hashes. Sketches built from the same
+// arguments are identical.
+func fuzzSketch(p uint8, seed int64, n uint16, normal bool) *HyperLogLogPlus {
+	h, _ := NewPlus(p)
+	if normal {
+		h.toNormal()
+	}
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < int(n); i++ {
+		h.Add(fakeHash64(r.Uint64()))
+	}
+	return h
+}
+
+// Returns the registers of h without modifying it.
+func fuzzRegisters(h *HyperLogLogPlus) []uint8 {
+	if !h.sparse {
+		return h.reg
+	}
+	reg := make([]uint8, h.m)
+	h.sparseToRegisters(reg)
+	return reg
+}
+
+func checkSameSketch(t *testing.T, name string, h1, h2 *HyperLogLogPlus) {
+	if !bytes.Equal(fuzzRegisters(h1), fuzzRegisters(h2)) {
+		t.Fatalf("%s: registers differ", name)
+	}
+	if c1, c2 := h1.Count(), h2.Count(); c1 != c2 {
+		t.Fatalf("%s: counts differ: %d, %d", name, c1, c2)
+	}
+}
+
+func FuzzMerge(f *testing.F) {
+	f.Add(uint8(8), int64(1), uint16(10), int64(2), uint16(20), int64(3), uint16(30), uint8(0))
+	f.Add(uint8(4), int64(1), uint16(100), int64(2), uint16(3), int64(3), uint16(0), uint8(5))
+	f.Add(uint8(14), int64(1), uint16(5000), int64(2), uint16(200), int64(3), uint16(1), uint8(2))
+	f.Fuzz(func(t *testing.T, p uint8, sa int64, na uint16, sb int64, nb uint16, sc int64, nc uint16, normal uint8) {
+		p = 4 + p%15
+		a := func() *HyperLogLogPlus { return fuzzSketch(p, sa, na, normal&1 != 0) }
+		b := func() *HyperLogLogPlus { return fuzzSketch(p, sb, nb, normal&2 != 0) }
+		c := func() *HyperLogLogPlus { return fuzzSketch(p, sc, nc, normal&4 != 0) }
+
+		// Commutative.
+		ab, ba := a(), b()
+		ab.Merge(b())
+		ba.Merge(a())
+		checkSameSketch(t, "a+b, b+a", ab, ba)
+
+		// Associative.
+		left, right := a(), b()
+		left.Merge(b())
+		left.Merge(c())
+		right.Merge(c())
+		right2 := a()
+		right2.Merge(right)
+		checkSameSketch(t, "(a+b)+c, a+(b+c)", left, right2)
+
+		// Idempotent.
+		aa := a()
+		aa.Merge(a())
+		checkSameSketch(t, "a+a, a", aa, a())
+	})
+}
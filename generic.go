@@ -0,0 +1,75 @@
+//go:build go1.23
+
+package hyperloglog
+
+import "iter"
+
+// Sketch is a type-safe HyperLogLogPlus that counts distinct values of type T,
+// hashing them with the function given to NewSketch.
+type Sketch[T any] struct {
+	h    *HyperLogLogPlus
+	hash func(T) uint64
+}
+
+// NewSketch returns a new Sketch with the given precision that hashes values
+// with hash. HashString, HashBytes and HashUint64 can be used to build hash
+// functions for most types.
+func NewSketch[T any](precision uint8, hash func(T) uint64) (*Sketch[T], error) {
+	h, err := NewPlus(precision)
+	if err != nil {
+		return nil, err
+	}
+	return &Sketch[T]{h, hash}, nil
+}
+
+// Add adds v to Sketch s.
+func (s *Sketch[T]) Add(v T) {
+	s.h.addHash(s.hash(v))
+}
+
+// The number of hashes AddSeq passes to AddHashes at once.
+const seqBatchSize = 1024
+
+// AddSeq adds every value in seq to Sketch s.
+func (s *Sketch[T]) AddSeq(seq iter.Seq[T]) {
+	addSeq(s.h, seq, s.hash)
+}
+
+func addSeq[T any](h *HyperLogLogPlus, seq iter.Seq[T], hash func(T) uint64) {
+	batch := make([]uint64, 0, seqBatchSize)
+	for v := range seq {
+		batch = append(batch, hash(v))
+		if len(batch) == seqBatchSize {
+			h.AddHashes(batch)
+			batch = batch[:0]
+		}
+	}
+	h.AddHashes(batch)
+}
+
+// Count returns the cardinality estimate.
+func (s *Sketch[T]) Count() uint64 {
+	return s.h.Count()
+}
+
+// Merge combines other with Sketch s. Both must hash values the same way.
+func (s *Sketch[T]) Merge(other *Sketch[T]) error {
+	return s.h.Merge(other.h)
+}
+
+// Plus returns the HyperLogLogPlus underlying Sketch s, for example to encode
+// it. Changes to either are seen by both.
+func (s *Sketch[T]) Plus() *HyperLogLogPlus {
+	return s.h
+}
+
+// CountDistinct estimates the number of distinct values in seq using a
+// HyperLogLogPlus of the given precision.
+func CountDistinct[T any](precision uint8, seq iter.Seq[T], hash func(T) uint64) (uint64, error) {
+	h, err := NewPlus(precision)
+	if err != nil {
+		return 0, err
+	}
+	addSeq(h, seq, hash)
+	return h.Count(), nil
+}
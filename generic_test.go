@@ -0,0 +1,94 @@
+//go:build go1.23
+
+package hyperloglog
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+type user struct {
+	org string
+	id  uint64
+}
+
+func hashUser(u user) uint64 {
+	return HashString(u.org) ^ HashUint64(u.id)
+}
+
+func TestSketch(t *testing.T) {
+	s, err := NewSketch(14, HashString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add("a")
+	s.Add("b")
+	s.Add("a")
+	if n := s.Count(); n != 2 {
+		t.Error(n)
+	}
+
+	words := make([]string, 5000)
+	for i := range words {
+		words[i] = string(rune('a'+i%26)) + string(rune('A'+i%1000/26)) + string(rune('0'+i%10))
+	}
+	s2, _ := NewSketch(14, HashString)
+	s2.AddSeq(slices.Values(words))
+
+	h, _ := NewPlus(14)
+	for _, w := range words {
+		h.Add(fakeHash64(HashString(w)))
+	}
+	if !s2.Plus().Equal(h) {
+		t.Error("AddSeq differs from Add")
+	}
+
+	if err := s.Merge(s2); err != nil {
+		t.Error(err)
+	}
+	h.Add(fakeHash64(HashString("a")))
+	h.Add(fakeHash64(HashString("b")))
+	if !s.Plus().Equal(h) {
+		t.Error("Merge differs")
+	}
+
+	if _, err := NewSketch(3, HashString); err == nil {
+		t.Error("expected precision error")
+	}
+}
+
+func TestSketchStruct(t *testing.T) {
+	s, _ := NewSketch(12, hashUser)
+	for i := 0; i < 300; i++ {
+		s.Add(user{"acme", uint64(i % 100)})
+		s.Add(user{"initech", uint64(i % 50)})
+	}
+	if n := s.Count(); n != 150 {
+		t.Error(n)
+	}
+}
+
+func TestCountDistinct(t *testing.T) {
+	m := make(map[uint64]bool)
+	for i := uint64(0); i < 3000; i++ {
+		m[i*7] = true
+	}
+	n, err := CountDistinct(14, maps.Keys(m), HashUint64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 2900 || n > 3100 {
+		t.Error(n)
+	}
+
+	ids := [][16]byte{{1}, {2}, {1}}
+	n, _ = CountDistinct(14, slices.Values(ids), func(id [16]byte) uint64 { return HashBytes(id[:]) })
+	if n != 2 {
+		t.Error(n)
+	}
+
+	if _, err := CountDistinct(30, slices.Values(ids), func([16]byte) uint64 { return 0 }); err == nil {
+		t.Error("expected precision error")
+	}
+}
@@ -0,0 +1,37 @@
+package hyperloglog
+
+import "hash/fnv"
+
+// HashString returns a 64-bit hash of s suitable for HyperLogLogPlus: FNV-1a
+// mixed with the MurmurHash3 finalizer, since FNV-1a alone leaves similar
+// short strings with similar high bits. The result is the same in every
+// process, so sketches built from it can be merged across processes.
+func HashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return fmix64(h.Sum64())
+}
+
+// HashBytes returns the same hash as HashString(string(b)).
+func HashBytes(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return fmix64(h.Sum64())
+}
+
+// HashUint64 returns a 64-bit hash of x suitable for HyperLogLogPlus. Distinct
+// values always have distinct hashes.
+func HashUint64(x uint64) uint64 {
+	return fmix64(x)
+}
+
+// The MurmurHash3 64-bit finalizer, a bijection that mixes every input bit
+// into every output bit.
+func fmix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,34 @@
+package hyperloglog
+
+import "testing"
+
+func TestHashString(t *testing.T) {
+	// The hashes must never change, or sketches built by different versions
+	// could not be merged.
+	for s, want := range map[string]uint64{
+		"":      0xefd01f60ba992926,
+		"hello": 0xe9c562c0fdb23244,
+	} {
+		if h := HashString(s); h != want {
+			t.Errorf("HashString(%q) = %#x, want %#x", s, h, want)
+		}
+		if h := HashBytes([]byte(s)); h != want {
+			t.Errorf("HashBytes(%q) = %#x, want %#x", s, h, want)
+		}
+	}
+	if h := HashUint64(1); h != 0xb456bcfc34c2cb2c {
+		t.Errorf("%#x", h)
+	}
+}
+
+func TestHashStringSpread(t *testing.T) {
+	// Similar short strings should land in different registers.
+	h, _ := NewPlus(14)
+	h.toNormal()
+	for i := 0; i < 1000; i++ {
+		h.addHash(HashString(string(rune('a'+i%26)) + string(rune('0'+i/26))))
+	}
+	if n := h.Count(); n < 950 || n > 1050 {
+		t.Error(n)
+	}
+}
@@ -0,0 +1,256 @@
+// Package httpapi serves named HyperLogLog++ sketches over HTTP, so that edge
+// collectors can ship partial sketches to a central aggregator.
+//
+// The routes are:
+//
+//	GET    /sketches                list the sketches and their estimates
+//	GET    /sketches/{name}         the sketch in the binary encoding, or as
+//	                                JSON with ?format=json
+//	GET    /sketches/{name}/count   the estimate and its error bounds
+//	POST   /sketches/{name}/items   add the strings in a JSON array, hashed
+//	                                with hyperloglog.HashString
+//	POST   /sketches/{name}/merge   merge a sketch in the binary encoding
+//
+// Items are hashed the same way as by cmd/hllserver, so sketches built by
+// either can be merged into the other.
+//
+// Adding items or merging into a sketch that does not exist creates it. All
+// sketches have the precision given to NewHandler, and merging a sketch with
+// another precision fails.
+//
+// Errors are returned as a JSON object with an "error" field.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+// The largest request body accepted, enough for a sketch of precision 24.
+const maxBodyLen = 32 << 20
+
+// Handler is an http.Handler holding named HyperLogLog++ sketches. It is safe
+// for concurrent use.
+type Handler struct {
+	mu        sync.RWMutex
+	sketches  map[string]*hyperloglog.HyperLogLogPlus
+	precision uint8
+}
+
+// Sketch describes a sketch in responses.
+type Sketch struct {
+	Name     string `json:"name"`
+	Estimate uint64 `json:"estimate"`
+}
+
+// Count is the response to a count request. Lower and Upper bound the
+// cardinality with about 95% confidence, using the relative standard error
+// 1.04/sqrt(m) of the normal representation.
+type Count struct {
+	Name     string  `json:"name"`
+	Estimate uint64  `json:"estimate"`
+	Error    float64 `json:"error"`
+	Lower    uint64  `json:"lower"`
+	Upper    uint64  `json:"upper"`
+}
+
+// NewHandler returns a Handler whose new sketches have the given precision.
+func NewHandler(precision uint8) (*Handler, error) {
+	if _, err := hyperloglog.NewPlus(precision); err != nil {
+		return nil, err
+	}
+	h := &Handler{
+		sketches:  make(map[string]*hyperloglog.HyperLogLogPlus),
+		precision: precision,
+	}
+	return h, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), "/sketches")
+	if !ok || (rest != "" && rest[0] != '/') {
+		writeError(w, http.StatusNotFound, errNoRoute)
+		return
+	}
+
+	var name, action string
+	if rest != "" {
+		var err error
+		name, action, _ = strings.Cut(rest[1:], "/")
+		if name, err = url.PathUnescape(name); err != nil || name == "" {
+			writeError(w, http.StatusNotFound, errNoRoute)
+			return
+		}
+	}
+
+	var handle func(http.ResponseWriter, *http.Request, string)
+	allowed := "GET"
+	switch {
+	case rest == "":
+		handle = h.list
+	case action == "":
+		handle = h.get
+	case action == "count":
+		handle = h.count
+	case action == "items":
+		handle, allowed = h.addItems, "POST"
+	case action == "merge":
+		handle, allowed = h.merge, "POST"
+	default:
+		writeError(w, http.StatusNotFound, errNoRoute)
+		return
+	}
+	if r.Method != allowed && !(allowed == "GET" && r.Method == "HEAD") {
+		w.Header().Set("Allow", allowed)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	handle(w, r, name)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+var (
+	errNotFound = errors.New("sketch not found")
+	errNoRoute  = errors.New("not found")
+)
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ string) {
+	h.mu.RLock()
+	sketches := make([]Sketch, 0, len(h.sketches))
+	for name, s := range h.sketches {
+		sketches = append(sketches, Sketch{name, s.Count()})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(sketches, func(i, j int) bool { return sketches[i].Name < sketches[j].Name })
+	writeJSON(w, http.StatusOK, sketches)
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, name string) {
+	h.mu.RLock()
+	s, ok := h.sketches[name]
+	var b []byte
+	var err error
+	if ok {
+		if r.URL.Query().Get("format") == "json" {
+			b, err = s.MarshalJSON()
+		} else {
+			b, err = s.MarshalBinary()
+		}
+	}
+	h.mu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	w.Write(b)
+}
+
+func (h *Handler) count(w http.ResponseWriter, r *http.Request, name string) {
+	h.mu.RLock()
+	s, ok := h.sketches[name]
+	var est uint64
+	if ok {
+		est = s.Count()
+	}
+	h.mu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+
+	e := 1.04 / math.Sqrt(float64(uint64(1)<<h.precision))
+	writeJSON(w, http.StatusOK, Count{
+		Name:     name,
+		Estimate: est,
+		Error:    e,
+		Lower:    uint64(math.Max(0, math.Floor(float64(est)*(1-2*e)))),
+		Upper:    uint64(math.Ceil(float64(est) * (1 + 2*e))),
+	})
+}
+
+func (h *Handler) addItems(w http.ResponseWriter, r *http.Request, name string) {
+	var items []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyLen)).Decode(&items); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	hashes := make([]uint64, len(items))
+	for i, item := range items {
+		hashes[i] = hyperloglog.HashString(item)
+	}
+
+	h.mu.Lock()
+	s, ok := h.sketches[name]
+	if !ok {
+		s, _ = hyperloglog.NewPlus(h.precision)
+		h.sketches[name] = s
+	}
+	s.AddHashes(hashes)
+	est := s.Count()
+	h.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, Sketch{name, est})
+}
+
+func (h *Handler) merge(w http.ResponseWriter, r *http.Request, name string) {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyLen))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	other := &hyperloglog.HyperLogLogPlus{}
+	if err := other.UnmarshalBinary(b); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.mu.Lock()
+	s, ok := h.sketches[name]
+	if !ok {
+		s, _ = hyperloglog.NewPlus(h.precision)
+	}
+	err = s.Merge(other)
+	if err == nil {
+		h.sketches[name] = s
+	}
+	est := s.Count()
+	h.mu.Unlock()
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Sketch{name, est})
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+func do(t *testing.T, h http.Handler, method, path string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewReader(body)))
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatal(err, w.Body.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h, err := NewHandler(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := do(t, h, "POST", "/sketches/a/items", []byte(`["x", "y", "z", "x"]`))
+	var s Sketch
+	decode(t, w, &s)
+	if w.Code != http.StatusOK || s != (Sketch{"a", 3}) {
+		t.Error(w.Code, s)
+	}
+
+	w = do(t, h, "GET", "/sketches/a/count", nil)
+	var c Count
+	decode(t, w, &c)
+	if c.Name != "a" || c.Estimate != 3 || c.Error != 1.04/64 || c.Lower != 2 || c.Upper != 4 {
+		t.Error(c)
+	}
+
+	// An edge collector builds its own sketch and ships it.
+	edge, _ := hyperloglog.NewPlus(12)
+	for _, item := range []string{"z", "w"} {
+		edge.AddHashes([]uint64{hyperloglog.HashString(item)})
+	}
+	b, _ := edge.MarshalBinary()
+	w = do(t, h, "POST", "/sketches/a/merge", b)
+	decode(t, w, &s)
+	if w.Code != http.StatusOK || s.Estimate != 4 {
+		t.Error(w.Code, s)
+	}
+	w = do(t, h, "POST", "/sketches/b/merge", b)
+	decode(t, w, &s)
+	if w.Code != http.StatusOK || s != (Sketch{"b", 2}) {
+		t.Error(w.Code, s)
+	}
+
+	w = do(t, h, "GET", "/sketches", nil)
+	var list []Sketch
+	decode(t, w, &list)
+	if len(list) != 2 || list[0] != (Sketch{"a", 4}) || list[1] != (Sketch{"b", 2}) {
+		t.Error(list)
+	}
+
+	w = do(t, h, "GET", "/sketches/b", nil)
+	if w.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Error(w.Header())
+	}
+	var got hyperloglog.HyperLogLogPlus
+	if err := got.UnmarshalBinary(w.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(edge) {
+		t.Error("sketch differs")
+	}
+
+	w = do(t, h, "GET", "/sketches/b?format=json", nil)
+	got = hyperloglog.HyperLogLogPlus{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(edge) {
+		t.Error("sketch differs")
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	h, _ := NewHandler(12)
+	other, _ := hyperloglog.NewPlus(10)
+	b, _ := other.MarshalBinary()
+
+	for _, tc := range []struct {
+		method, path string
+		body         []byte
+		code         int
+	}{
+		{"GET", "/sketches/missing", nil, http.StatusNotFound},
+		{"GET", "/sketches/missing/count", nil, http.StatusNotFound},
+		{"POST", "/sketches/a/items", []byte(`{"x": 1}`), http.StatusBadRequest},
+		{"POST", "/sketches/a/items", []byte(`[1, 2]`), http.StatusBadRequest},
+		{"POST", "/sketches/a/merge", []byte("junk"), http.StatusBadRequest},
+		{"POST", "/sketches/a/merge", b, http.StatusBadRequest},
+		{"DELETE", "/sketches/a", nil, http.StatusMethodNotAllowed},
+		{"GET", "/sketches/a/items", nil, http.StatusMethodNotAllowed},
+		{"GET", "/sketchesx", nil, http.StatusNotFound},
+		{"GET", "/sketches/", nil, http.StatusNotFound},
+		{"GET", "/sketches/a/other", nil, http.StatusNotFound},
+	} {
+		w := do(t, h, tc.method, tc.path, tc.body)
+		if w.Code != tc.code {
+			t.Error(tc.method, tc.path, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), `"error"`) {
+			t.Error(w.Body.String())
+		}
+	}
+
+	w := do(t, h, "GET", "/sketches", nil)
+	if body, _ := io.ReadAll(w.Body); strings.TrimSpace(string(body)) != "[]" {
+		t.Error("failed requests created sketches", string(body))
+	}
+}
+
+func TestHandlerEscapedName(t *testing.T) {
+	h, _ := NewHandler(12)
+	do(t, h, "POST", "/sketches/a%2Fb/items", []byte(`["x"]`))
+
+	w := do(t, h, "GET", "/sketches", nil)
+	var list []Sketch
+	decode(t, w, &list)
+	if len(list) != 1 || list[0] != (Sketch{"a/b", 1}) {
+		t.Error(list)
+	}
+	if w := do(t, h, "GET", "/sketches/a%2Fb/count", nil); w.Code != http.StatusOK {
+		t.Error(w.Code)
+	}
+}
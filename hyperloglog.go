@@ -15,15 +15,17 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"io"
 	"math"
 )
 
 const two32 = 1 << 32
 
 type HyperLogLog struct {
-	reg []uint8
-	m   uint32
-	p   uint8
+	reg  []uint8
+	m    uint32
+	p    uint8
+	wide bool
 }
 
 // New returns a new initialized HyperLogLog.
@@ -39,14 +41,60 @@ func New(precision uint8) (*HyperLogLog, error) {
 	return h, nil
 }
 
+// New64 returns a new initialized HyperLogLog that uses 64-bit hashes. Items
+// should be added with Add64 or AddHashes64. Because the hash space is not
+// limited to 32 bits, Count does not need the large range correction of the
+// original algorithm.
+func New64(precision uint8) (*HyperLogLog, error) {
+	h, err := New(precision)
+	if err != nil {
+		return nil, err
+	}
+	h.wide = true
+	return h, nil
+}
+
 // Clear sets HyperLogLog h back to its initial state.
 func (h *HyperLogLog) Clear() {
 	h.reg = make([]uint8, h.m)
 }
 
-// Add adds a new item to HyperLogLog h.
+// Clone returns a copy of HyperLogLog h that shares no state with it.
+func (h *HyperLogLog) Clone() *HyperLogLog {
+	c := *h
+	c.reg = append([]uint8(nil), h.reg...)
+	return &c
+}
+
+// Equal reports whether HyperLogLog h and other have the same precision, hash
+// size and registers.
+func (h *HyperLogLog) Equal(other *HyperLogLog) bool {
+	return h.p == other.p && h.wide == other.wide && bytes.Equal(h.reg, other.reg)
+}
+
+// Add adds a new item to HyperLogLog h. For a HyperLogLog created with New64,
+// the 32-bit hash is used as the upper half of a 64-bit hash.
 func (h *HyperLogLog) Add(item Hash32) {
 	x := item.Sum32()
+	if h.wide {
+		h.add64(uint64(x) << 32)
+		return
+	}
+	h.add32(x)
+}
+
+// Add64 adds a new item with a 64-bit hash to HyperLogLog h. For a
+// HyperLogLog created with New, only the upper 32 bits of the hash are used.
+func (h *HyperLogLog) Add64(item Hash64) {
+	x := item.Sum64()
+	if !h.wide {
+		h.add32(uint32(x >> 32))
+		return
+	}
+	h.add64(x)
+}
+
+func (h *HyperLogLog) add32(x uint32) {
 	i := eb32(x, 32, 32-h.p) // {x31,...,x32-p}
 	w := x<<h.p | 1<<(h.p-1) // {x32-p,...,x0}
 
@@ -56,17 +104,74 @@ func (h *HyperLogLog) Add(item Hash32) {
 	}
 }
 
+func (h *HyperLogLog) add64(x uint64) {
+	i := eb64(x, 64, 64-h.p) // {x63,...,x64-p}
+	w := x<<h.p | 1<<(h.p-1) // {x63-p,...,x0}
+
+	zeroBits := clz64(w) + 1
+	if zeroBits > h.reg[i] {
+		h.reg[i] = zeroBits
+	}
+}
+
+// AddHashes adds a batch of already hashed items to HyperLogLog h. For a
+// HyperLogLog created with New64, each hash is used as the upper half of a
+// 64-bit hash.
+func (h *HyperLogLog) AddHashes(xs []uint32) {
+	if h.wide {
+		for _, x := range xs {
+			h.add64(uint64(x) << 32)
+		}
+		return
+	}
+
+	p := h.p
+	reg := h.reg
+	for _, x := range xs {
+		i := x >> (32 - p)   // {x31,...,x32-p}
+		w := x<<p | 1<<(p-1) // {x32-p,...,x0}
+
+		zeroBits := clz32(w) + 1
+		if zeroBits > reg[i] {
+			reg[i] = zeroBits
+		}
+	}
+}
+
+// AddHashes64 adds a batch of items with 64-bit hashes to HyperLogLog h. For
+// a HyperLogLog created with New, only the upper 32 bits of each hash are
+// used.
+func (h *HyperLogLog) AddHashes64(xs []uint64) {
+	if !h.wide {
+		for _, x := range xs {
+			h.add32(uint32(x >> 32))
+		}
+		return
+	}
+
+	p := h.p
+	reg := h.reg
+	for _, x := range xs {
+		i := x >> (64 - p)   // {x63,...,x64-p}
+		w := x<<p | 1<<(p-1) // {x63-p,...,x0}
+
+		zeroBits := clz64(w) + 1
+		if zeroBits > reg[i] {
+			reg[i] = zeroBits
+		}
+	}
+}
+
 // Merge takes another HyperLogLog and combines it with HyperLogLog h.
 func (h *HyperLogLog) Merge(other *HyperLogLog) error {
 	if h.p != other.p {
 		return errors.New("precisions must be equal")
 	}
-
-	for i, v := range other.reg {
-		if v > h.reg[i] {
-			h.reg[i] = v
-		}
+	if h.wide != other.wide {
+		return errors.New("hash sizes must be equal")
 	}
+
+	maxRegisters(h.reg, other.reg)
 	return nil
 }
 
@@ -78,7 +183,7 @@ func (h *HyperLogLog) Count() uint64 {
 			return uint64(linearCounting(h.m, v))
 		}
 		return uint64(est)
-	} else if est < two32/30 {
+	} else if h.wide || est < two32/30 {
 		return uint64(est)
 	}
 	return uint64(-two32 * math.Log(1-est/two32))
@@ -97,20 +202,45 @@ func (h *HyperLogLog) GobEncode() ([]byte, error) {
 	if err := enc.Encode(h.p); err != nil {
 		return nil, err
 	}
+	if err := enc.Encode(h.wide); err != nil {
+		return nil, err
+	}
 	return buf.Bytes(), nil
 }
 
 // Decode gob into a HyperLogLog structure
 func (h *HyperLogLog) GobDecode(b []byte) error {
+	var g HyperLogLog
 	dec := gob.NewDecoder(bytes.NewBuffer(b))
-	if err := dec.Decode(&h.reg); err != nil {
+	if err := dec.Decode(&g.reg); err != nil {
 		return err
 	}
-	if err := dec.Decode(&h.m); err != nil {
+	if err := dec.Decode(&g.m); err != nil {
 		return err
 	}
-	if err := dec.Decode(&h.p); err != nil {
+	if err := dec.Decode(&g.p); err != nil {
 		return err
 	}
+	// Gobs written before 64-bit hashes were supported end here.
+	if err := dec.Decode(&g.wide); err != nil && err != io.EOF {
+		return err
+	}
+
+	if err := g.validate(); err != nil {
+		return err
+	}
+	*h = g
+	return nil
+}
+
+// Checks that a decoded HyperLogLog is consistent, so that using it cannot
+// panic.
+func (h *HyperLogLog) validate() error {
+	if h.p < 4 || h.p > 16 {
+		return errors.New("precision must be between 4 and 16")
+	}
+	if h.m != 1<<h.p || len(h.reg) != int(h.m) {
+		return errors.New("register count does not match precision")
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package hyperloglog
 import (
 	"bytes"
 	"encoding/gob"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -202,3 +203,155 @@ func TestHLLGob(t *testing.T) {
 		t.Error("unmarshaled structure differs")
 	}
 }
+
+func TestHLLAddHashes(t *testing.T) {
+	h, _ := New(16)
+	h2, _ := New(16)
+
+	xs := []uint32{0x00010fff, 0x0002ffff, 0x00030000, 0x00030001, 0xff037000, 0xff030800}
+	for _, x := range xs {
+		h.Add(fakeHash32(x))
+	}
+	h2.AddHashes(xs)
+
+	if !reflect.DeepEqual(h.reg, h2.reg) {
+		t.Error("AddHashes registers differ from Add")
+	}
+
+	n := h2.reg[0xff03]
+	if n != 5 {
+		t.Error(n)
+	}
+}
+
+func TestHLL64Add(t *testing.T) {
+	h, _ := New64(16)
+
+	h.Add64(fakeHash64(0x00010fffffffffff))
+	n := h.reg[1]
+	if n != 5 {
+		t.Error(n)
+	}
+
+	h.Add64(fakeHash64(0x0003000000000000))
+	n = h.reg[3]
+	if n != 49 {
+		t.Error(n)
+	}
+
+	h.AddHashes64([]uint64{0xff03700000000000, 0xff03080000000000})
+	n = h.reg[0xff03]
+	if n != 5 {
+		t.Error(n)
+	}
+
+	// 32-bit hashes are widened rather than truncated.
+	h.Add(fakeHash32(0x00040000))
+	n = h.reg[4]
+	if n != 49 {
+		t.Error(n)
+	}
+
+	h2, _ := New(16)
+	h2.Add64(fakeHash64(0x00010fffffffffff))
+	n = h2.reg[1]
+	if n != 5 {
+		t.Error(n)
+	}
+}
+
+func TestHLL64Count(t *testing.T) {
+	h, _ := New64(16)
+	for _, x := range []uint64{0x00010fffffffffff, 0x00020fffffffffff, 0x00030fffffffffff} {
+		h.Add64(fakeHash64(x))
+	}
+	n := h.Count()
+	if n != 3 {
+		t.Error(n)
+	}
+
+	// Far beyond the range of 32-bit hashes, where the large range correction
+	// of the 32-bit algorithm no longer applies.
+	for i := range h.reg {
+		h.reg[i] = 40
+	}
+	n = h.Count()
+	want := alpha(h.m) * float64(h.m) * (1 << 40)
+	if math.Abs(float64(n)-want)/want > 0.0001 {
+		t.Error(n, want)
+	}
+}
+
+func TestHLL64MergeError(t *testing.T) {
+	h, _ := New(16)
+	h2, _ := New64(16)
+
+	err := h.Merge(h2)
+	if err == nil {
+		t.Error("different hash sizes should return error")
+	}
+}
+
+func TestHLL64Gob(t *testing.T) {
+	h, _ := New64(8)
+	h.Add64(fakeHash64(0x10fffffffffff))
+
+	b, err := h.GobEncode()
+	if err != nil {
+		t.Error(err)
+	}
+
+	var h2 HyperLogLog
+	if err := h2.GobDecode(b); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(h, &h2) {
+		t.Error("unmarshaled structure differs")
+	}
+}
+
+func TestHLLGobWithoutWide(t *testing.T) {
+	h, _ := New(4)
+	h.Add(fakeHash32(0x1fffffff))
+
+	// Encoded the way HyperLogLog was before New64 existed.
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	enc.Encode(h.reg)
+	enc.Encode(h.m)
+	enc.Encode(h.p)
+
+	h2, _ := New64(4)
+	if err := h2.GobDecode(buf.Bytes()); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(h, h2) {
+		t.Error("unmarshaled structure differs")
+	}
+}
+
+func TestHLLCloneEqual(t *testing.T) {
+	h, _ := New(8)
+	h.Add(fakeHash32(0x00010fff))
+
+	c := h.Clone()
+	if !h.Equal(c) {
+		t.Error("clone differs")
+	}
+	c.Add(fakeHash32(0x02000fff))
+	if h.Equal(c) || h.reg[2] != 0 {
+		t.Error("adding to clone changed original")
+	}
+
+	h64, _ := New64(8)
+	h64.Add(fakeHash32(0x00010fff))
+	h2, _ := New(8)
+	h2.Add(fakeHash32(0x00010fff))
+	if h2.Equal(h64) {
+		t.Error("different hash sizes should differ")
+	}
+	h3, _ := New(9)
+	if h3.Equal(h) {
+		t.Error("different precisions should differ")
+	}
+}
@@ -7,13 +7,10 @@ import (
 	"sort"
 )
 
-const pPrime = 25
-const mPrime = 1 << (pPrime - 1)
+//go:generate go run gendata.go -trials 100 -o data_simulated.go
 
-var threshold = []uint{
-	10, 20, 40, 80, 220, 400, 900, 1800, 3100,
-	6500, 11500, 20000, 50000, 120000, 350000,
-}
+const pPrime = 25
+const mPrime = 1 << pPrime
 
 type HyperLogLogPlus struct {
 	reg        []uint8
@@ -57,6 +54,20 @@ func (h *HyperLogLogPlus) decodeHash(k uint32) (uint32, uint8) {
 // Merge tmpSet and sparseList in the sparse representation.
 // Converts to normal if the sparse list is too large
 func (h *HyperLogLogPlus) mergeSparse() {
+	h.sparseList = h.mergedList()
+	h.tmpSet = set{}
+
+	if uint32(h.sparseList.Len()) > h.m {
+		h.toNormal()
+	}
+}
+
+// Returns the sparse list with tmpSet merged into it, without modifying h.
+func (h *HyperLogLogPlus) mergedList() *compressedList {
+	if len(h.tmpSet) == 0 {
+		return h.sparseList
+	}
+
 	keys := make(sortableSlice, 0, len(h.tmpSet))
 	for k := range h.tmpSet {
 		keys = append(keys, k)
@@ -87,16 +98,24 @@ func (h *HyperLogLogPlus) mergeSparse() {
 			newList.Append(iter.Next())
 		}
 	}
+	return newList
+}
 
-	h.sparseList = newList
-	h.tmpSet = set{}
-
-	if uint32(h.sparseList.Len()) > h.m {
-		h.toNormal()
+// Returns the registers of HyperLogLogPlus h, decoding them from the sparse
+// representation without modifying h if necessary.
+func (h *HyperLogLogPlus) registers() []uint8 {
+	if !h.sparse {
+		return h.reg
 	}
+	reg := make([]uint8, h.m)
+	h.sparseToRegisters(reg)
+	return reg
 }
 
 func (h *HyperLogLogPlus) mergeSparseAndToNormal() {
+	if !h.sparse {
+		return
+	}
 	h.mergeSparse()
 	if h.sparse {
 		h.toNormal()
@@ -104,10 +123,11 @@ func (h *HyperLogLogPlus) mergeSparseAndToNormal() {
 }
 
 // NewPlus returns a new initialized HyperLogLogPlus that uses the HyperLogLog++
-// algorithm.
+// algorithm. The HyperLogLog++ paper gives bias correction data for precisions
+// up to 18, and gendata.go simulates it for higher precisions.
 func NewPlus(precision uint8) (*HyperLogLogPlus, error) {
-	if precision > 18 || precision < 4 {
-		return nil, errors.New("precision must be between 4 and 18")
+	if precision > pPrime-1 || precision < 4 {
+		return nil, errors.New("precision must be between 4 and 24")
 	}
 
 	h := &HyperLogLogPlus{}
@@ -127,16 +147,60 @@ func (h *HyperLogLogPlus) Clear() {
 	h.reg = nil
 }
 
+// Clone returns a copy of HyperLogLogPlus h that shares no state with it. In
+// the sparse representation only the sparse list and temporary set are copied.
+func (h *HyperLogLogPlus) Clone() *HyperLogLogPlus {
+	c := *h
+	if h.sparse {
+		c.tmpSet = make(set, len(h.tmpSet))
+		for k := range h.tmpSet {
+			c.tmpSet.Add(k)
+		}
+		l := *h.sparseList
+		l.b = append(variableLengthList(nil), l.b...)
+		c.sparseList = &l
+	} else {
+		c.reg = append([]uint8(nil), h.reg...)
+	}
+	return &c
+}
+
+// Equal reports whether HyperLogLogPlus h and other have the same precision
+// and logical state, so that equal sketches always have the same Count. Sparse
+// sketches are compared by their sparse lists as if their temporary sets were
+// merged into them, so it does not matter which items are still waiting to be
+// merged. A sparse sketch whose merged list would be converted to the normal
+// representation is compared by its registers, like a normal sketch, and is
+// never equal to one that would stay sparse. Neither sketch is modified.
+func (h *HyperLogLogPlus) Equal(other *HyperLogLogPlus) bool {
+	if h.p != other.p {
+		return false
+	}
+
+	l1, l2 := h.effectiveList(), other.effectiveList()
+	if l1 != nil || l2 != nil {
+		return l1 != nil && l2 != nil && bytes.Equal(l1.b, l2.b)
+	}
+	return bytes.Equal(h.registers(), other.registers())
+}
+
+// Returns the merged sparse list of HyperLogLogPlus h, or nil if h is normal
+// or its merged list is too long to stay sparse.
+func (h *HyperLogLogPlus) effectiveList() *compressedList {
+	if !h.sparse {
+		return nil
+	}
+	if l := h.mergedList(); uint32(l.Len()) <= h.m {
+		return l
+	}
+	return nil
+}
+
 // Converts HyperLogLogPlus h to the normal representation from the sparse
 // representation.
 func (h *HyperLogLogPlus) toNormal() {
 	h.reg = make([]uint8, h.m)
-	for iter := h.sparseList.Iter(); iter.HasNext(); {
-		i, r := h.decodeHash(iter.Next())
-		if h.reg[i] < r {
-			h.reg[i] = r
-		}
-	}
+	h.sparseToRegisters(h.reg)
 
 	h.sparse = false
 	h.tmpSet = nil
@@ -145,7 +209,10 @@ func (h *HyperLogLogPlus) toNormal() {
 
 // Add adds a new item to HyperLogLogPlus h.
 func (h *HyperLogLogPlus) Add(item Hash64) {
-	x := item.Sum64()
+	h.addHash(item.Sum64())
+}
+
+func (h *HyperLogLogPlus) addHash(x uint64) {
 	if h.sparse {
 		h.tmpSet.Add(h.encodeHash(x))
 		h.maybeMerge()
@@ -160,6 +227,67 @@ func (h *HyperLogLogPlus) Add(item Hash64) {
 	}
 }
 
+// AddHashes adds a batch of already hashed items to HyperLogLogPlus h.
+//
+// In the sparse representation the batch is encoded in chunks of at most m
+// hashes, each of which is merged into the sparse list once. If the sketch
+// converts to the normal representation part way through, the rest of the
+// batch goes straight into the registers.
+func (h *HyperLogLogPlus) AddHashes(xs []uint64) {
+	h.addHashes(xs, false)
+}
+
+// AddHashesChanged is like AddHashes, but also reports whether h changed: in
+// the normal representation, whether any register grew, and in the sparse
+// representation, whether any hash added an entry that was not already in the
+// sparse list or temporary set. It costs little more than AddHashes, except
+// that a sparse batch with new temporary set entries is checked against the
+// sparse list.
+func (h *HyperLogLogPlus) AddHashesChanged(xs []uint64) bool {
+	return h.addHashes(xs, true)
+}
+
+// Adds a batch of hashes to h. If track is set, it reports whether h changed;
+// otherwise it only reports whether a register grew.
+func (h *HyperLogLogPlus) addHashes(xs []uint64, track bool) bool {
+	var changed bool
+	for len(xs) > 0 && h.sparse {
+		n := len(xs)
+		if n > int(h.m) {
+			n = int(h.m)
+		}
+		var added sortableSlice
+		for _, x := range xs[:n] {
+			k := h.encodeHash(x)
+			if track && !changed && !h.tmpSet[k] {
+				added = append(added, k)
+			}
+			h.tmpSet.Add(k)
+		}
+		// Check before maybeMerge moves the new entries into the list.
+		if len(added) > 0 {
+			sort.Sort(added)
+			changed = !h.sparseList.containsAll(added)
+		}
+		h.maybeMerge()
+		xs = xs[n:]
+	}
+
+	p := h.p
+	reg := h.reg
+	for _, x := range xs {
+		i := x >> (64 - p)   // {x63,...,x64-p}
+		w := x<<p | 1<<(p-1) // {x63-p,...,x0}
+
+		zeroBits := clz64(w) + 1
+		if zeroBits > reg[i] {
+			reg[i] = zeroBits
+			changed = true
+		}
+	}
+	return changed
+}
+
 // Merge takes another HyperLogLogPlus and combines it with HyperLogLogPlus h.
 func (h *HyperLogLogPlus) Merge(other *HyperLogLogPlus) error {
 	if h.p != other.p {
@@ -182,29 +310,86 @@ func (h *HyperLogLogPlus) Merge(other *HyperLogLogPlus) error {
 	}
 
 	if other.sparse {
-		for k := range other.tmpSet {
-			i, r := other.decodeHash(k)
-			if r > h.reg[i] {
-				h.reg[i] = r
-			}
+		other.sparseToRegisters(h.reg)
+	} else {
+		maxRegisters(h.reg, other.reg)
+	}
+	return nil
+}
+
+// MergeAll combines each of others with HyperLogLogPlus h. The result is the
+// same as calling Merge with each of others in turn, but sparse sketches are
+// combined with a single k-way merge of their sparse lists, and sketches in
+// the normal representation are merged in parallel over ranges of registers.
+func (h *HyperLogLogPlus) MergeAll(others ...*HyperLogLogPlus) error {
+	var sparse, normal []*HyperLogLogPlus
+	for _, other := range others {
+		if h.p != other.p {
+			return errors.New("precisions must be equal")
+		}
+		if other == h {
+			continue
 		}
 
-		for iter := other.sparseList.Iter(); iter.HasNext(); {
-			i, r := other.decodeHash(iter.Next())
-			if r > h.reg[i] {
-				h.reg[i] = r
-			}
+		if other.sparse {
+			sparse = append(sparse, other)
+		} else {
+			normal = append(normal, other)
 		}
-	} else {
-		for i, v := range other.reg {
-			if v > h.reg[i] {
-				h.reg[i] = v
+	}
+
+	// The inputs may share many entries, so only the merged list shows whether
+	// the result still fits in the sparse representation. Merging stops as soon
+	// as it does not, and the rest is merged into registers instead.
+	if h.sparse && len(normal) == 0 {
+		lists := []*compressedList{h.sparseList, h.tmpSet.sortedList()}
+		for _, other := range sparse {
+			lists = append(lists, other.sparseList)
+			if len(other.tmpSet) > 0 {
+				lists = append(lists, other.tmpSet.sortedList())
 			}
 		}
+
+		if l, ok := mergeLists(int(h.m), lists...); ok {
+			h.sparseList = l
+			h.tmpSet = set{}
+			return nil
+		}
+	}
+
+	if h.sparse {
+		h.mergeSparseAndToNormal()
+	}
+
+	for _, other := range sparse {
+		other.sparseToRegisters(h.reg)
 	}
+
+	srcs := make([][]uint8, 0, len(normal))
+	for _, other := range normal {
+		srcs = append(srcs, other.reg)
+	}
+	mergeRegisters(h.reg, srcs)
 	return nil
 }
 
+// Merges the sparse representation of HyperLogLogPlus h into registers reg.
+func (h *HyperLogLogPlus) sparseToRegisters(reg []uint8) {
+	for k := range h.tmpSet {
+		i, r := h.decodeHash(k)
+		if r > reg[i] {
+			reg[i] = r
+		}
+	}
+
+	for iter := h.sparseList.Iter(); iter.HasNext(); {
+		i, r := h.decodeHash(iter.Next())
+		if r > reg[i] {
+			reg[i] = r
+		}
+	}
+}
+
 // Merges tmpSet if it exceeds the threshold
 func (h *HyperLogLogPlus) maybeMerge() {
 	if uint32(len(h.tmpSet))*100 > h.m {
@@ -212,9 +397,42 @@ func (h *HyperLogLogPlus) maybeMerge() {
 	}
 }
 
-// Estimates the bias using empirically determined values.
-func (h *HyperLogLogPlus) estimateBias(est float64) float64 {
-	estTable, biasTable := rawEstimateData[h.p-4], biasData[h.p-4]
+// Counts the distinct indices at precision pPrime in sparse list l. Entries
+// that encode their rank can share an index with each other, but since they
+// sort by index then rank, such entries are adjacent among the entries that
+// encode their rank.
+func sparseIndices(l *compressedList) uint32 {
+	n := l.Count
+	last := ^uint32(0)
+	for iter := l.Iter(); iter.HasNext(); {
+		k := iter.Next()
+		if k&1 == 0 {
+			continue
+		}
+		if k>>7 == last {
+			n--
+		}
+		last = k >> 7
+	}
+	return n
+}
+
+// Returns the empirically determined raw estimates, their biases and the
+// linear counting threshold for precision p: the paper's if it covers p, and
+// otherwise simulated ones. It returns false if there are none.
+func biasTables(p uint8) (estTable, biasTable []float64, lcThreshold uint, ok bool) {
+	if i := int(p) - 4; i < len(biasData) {
+		return rawEstimateData[i], biasData[i], threshold[i], true
+	}
+	if i := int(p) - simulatedMinPrecision; i >= 0 && i < len(simulatedBiasData) {
+		return simulatedRawEstimateData[i], simulatedBiasData[i], simulatedThreshold[i], true
+	}
+	return nil, nil, 0, false
+}
+
+// Estimates the bias at precision p using empirically determined values.
+func biasAt(p uint8, est float64) float64 {
+	estTable, biasTable, _, _ := biasTables(p)
 
 	if estTable[0] > est {
 		return biasTable[0]
@@ -236,30 +454,53 @@ func (h *HyperLogLogPlus) estimateBias(est float64) float64 {
 	return b1*(1-c) + b2*c
 }
 
-// Count returns the cardinality estimate.
+// Count returns the cardinality estimate. It does not modify h, so it is safe
+// to call concurrently with other reads.
 func (h *HyperLogLogPlus) Count() uint64 {
 	if h.sparse {
-		h.mergeSparse()
+		l := h.mergedList()
+		if uint32(l.Len()) <= h.m {
+			return uint64(linearCounting(mPrime, mPrime-sparseIndices(l)))
+		}
 	}
 
-	if h.sparse {
-		return uint64(linearCounting(mPrime, mPrime-uint32(h.sparseList.Count)))
+	return estimateRegisters(h.registers(), h.p)
+}
+
+// Estimates the cardinality from the registers of a normal HyperLogLog++
+// sketch of precision p, with bias correction and linear counting for small
+// cardinalities.
+func estimateRegisters(reg []uint8, p uint8) uint64 {
+	_, _, lcThreshold, ok := biasTables(p)
+	if !ok {
+		return uint64(ertlEstimate(reg, 64-p))
 	}
 
-	est := calculateEstimate(h.reg)
-	if est <= float64(h.m)*5.0 {
-		est -= h.estimateBias(est)
+	m := uint32(len(reg))
+	est := calculateEstimate(reg)
+	if est <= float64(m)*5.0 {
+		est -= biasAt(p, est)
 	}
 
-	if v := countZeros(h.reg); v != 0 {
-		lc := linearCounting(h.m, v)
-		if lc <= float64(threshold[h.p-4]) {
+	if v := countZeros(reg); v != 0 {
+		lc := linearCounting(m, v)
+		if lc <= float64(lcThreshold) {
 			return uint64(lc)
 		}
 	}
 	return uint64(est)
 }
 
+// Flush merges the temporary set of a sparse HyperLogLogPlus h into its sparse
+// list, converting h to the normal representation if the list grows too large.
+// Count does not need h to be flushed, but calling Flush after a batch of adds
+// saves Count from repeating the merge every time it is called.
+func (h *HyperLogLogPlus) Flush() {
+	if h.sparse {
+		h.mergeSparse()
+	}
+}
+
 // Encode HyperLogLogPlus into a gob
 func (h *HyperLogLogPlus) GobEncode() ([]byte, error) {
 	buf := bytes.Buffer{}
@@ -295,33 +536,70 @@ func (h *HyperLogLogPlus) GobEncode() ([]byte, error) {
 
 // Decode gob into a HyperLogLogPlus structure
 func (h *HyperLogLogPlus) GobDecode(b []byte) error {
+	var g HyperLogLogPlus
 	dec := gob.NewDecoder(bytes.NewBuffer(b))
-	if err := dec.Decode(&h.reg); err != nil {
+	if err := dec.Decode(&g.reg); err != nil {
 		return err
 	}
-	if err := dec.Decode(&h.m); err != nil {
+	if err := dec.Decode(&g.m); err != nil {
 		return err
 	}
-	if err := dec.Decode(&h.p); err != nil {
+	if err := dec.Decode(&g.p); err != nil {
 		return err
 	}
-	if err := dec.Decode(&h.sparse); err != nil {
+	if err := dec.Decode(&g.sparse); err != nil {
 		return err
 	}
-	if h.sparse {
-		if err := dec.Decode(&h.tmpSet); err != nil {
+	if g.sparse {
+		if err := dec.Decode(&g.tmpSet); err != nil {
 			return err
 		}
-		h.sparseList = newCompressedList(int(h.m))
-		if err := dec.Decode(&h.sparseList.Count); err != nil {
+		g.sparseList = &compressedList{}
+		if err := dec.Decode(&g.sparseList.Count); err != nil {
 			return err
 		}
-		if err := dec.Decode(&h.sparseList.b); err != nil {
+		if err := dec.Decode(&g.sparseList.b); err != nil {
 			return err
 		}
-		if err := dec.Decode(&h.sparseList.last); err != nil {
+		if err := dec.Decode(&g.sparseList.last); err != nil {
 			return err
 		}
 	}
+
+	if err := g.validate(); err != nil {
+		return err
+	}
+	if g.sparse && g.tmpSet == nil {
+		g.tmpSet = set{}
+	}
+	*h = g
+	return nil
+}
+
+// Checks that a decoded HyperLogLogPlus is consistent, so that using it
+// cannot panic.
+func (h *HyperLogLogPlus) validate() error {
+	if h.p < 4 || h.p > pPrime-1 {
+		return errors.New("precision must be between 4 and 24")
+	}
+	if h.m != 1<<h.p {
+		return errors.New("register count does not match precision")
+	}
+	if !h.sparse {
+		if len(h.reg) != int(h.m) {
+			return errors.New("register count does not match precision")
+		}
+		return nil
+	}
+
+	var n, x uint32
+	iter := h.sparseList.Iter()
+	for iter.HasNext() {
+		x = iter.Next()
+		n++
+	}
+	if iter.i != h.sparseList.Len() || n != h.sparseList.Count || x != h.sparseList.last {
+		return errors.New("invalid sparse list")
+	}
 	return nil
 }
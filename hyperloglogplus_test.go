@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"encoding/gob"
 	"math"
+	"math/rand"
 	"reflect"
+	"runtime"
+	"sync"
 	"testing"
 )
 
@@ -123,28 +126,43 @@ func TestHLLPPToNormal(t *testing.T) {
 	}
 }
 
+func TestHLLPPBiasTables(t *testing.T) {
+	// Every precision has tables, from the paper up to 18 and simulated above.
+	for p := uint8(4); p <= 24; p++ {
+		estTable, biasTable, lcThreshold, ok := biasTables(p)
+		if !ok || len(estTable) == 0 || len(estTable) != len(biasTable) {
+			t.Errorf("p=%d: missing tables", p)
+			continue
+		}
+		if m := uint(1) << p; lcThreshold == 0 || lcThreshold > 5*m {
+			t.Errorf("p=%d: threshold %d", p, lcThreshold)
+		}
+	}
+	if _, _, _, ok := biasTables(25); ok {
+		t.Error("p=25 should have no tables")
+	}
+}
+
 func TestHLLPPEstimateBias(t *testing.T) {
-	h, _ := NewPlus(4)
-	b := h.estimateBias(14.0988)
+	b := biasAt(4, 14.0988)
 	if math.Abs(b-7.5988) > 0.00001 {
 		t.Error(b)
 	}
 
 	// 10 is less than the first entry in the estimate table for p=4.
 	biasTable := biasData[0]
-	b = h.estimateBias(10)
+	b = biasAt(4, 10)
 	if math.Abs(b-biasTable[0]) > 0.00001 {
 		t.Error(b)
 	}
 
 	// 80 is greater than the first entry in the estimate table for p-4.
-	b = h.estimateBias(80)
+	b = biasAt(4, 80)
 	if math.Abs(b-biasTable[len(biasTable)-1]) > 0.00001 {
 		t.Error(b)
 	}
 
-	h, _ = NewPlus(16)
-	b = h.estimateBias(55391.4373)
+	b = biasAt(16, 55391.4373)
 	if math.Abs(b-39416.9373) > 0.00001 {
 		t.Error(b)
 	}
@@ -508,9 +526,14 @@ func TestHLLPPError(t *testing.T) {
 		t.Error(err)
 	}
 
-	_, err = NewPlus(19)
+	_, err = NewPlus(24)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = NewPlus(25)
 	if err == nil {
-		t.Error("precision 17 should return error")
+		t.Error("precision 25 should return error")
 	}
 }
 
@@ -574,12 +597,12 @@ func TestHLLPPToNormalWhenSparseIsTooBig(t *testing.T) {
 	}
 }
 
-func TestHLLPPToNormalWhenCountIsCalledOften(t *testing.T) {
+func TestHLLPPToNormalWhenFlushIsCalledOften(t *testing.T) {
 	h, _ := NewPlus(7)
 
 	for i := 0; i < 128; i++ {
 		h.Add(fakeHash64(i << 39))
-		h.Count()
+		h.Flush()
 	}
 
 	h.Add(fakeHash64(1))
@@ -588,9 +611,489 @@ func TestHLLPPToNormalWhenCountIsCalledOften(t *testing.T) {
 		t.Error("h should still be sparse")
 	}
 
-	h.Count()
+	h.Flush()
+
+	if h.sparse {
+		t.Error("h should be converted to normal")
+	}
+}
+
+func TestHLLPPCountDoesNotModify(t *testing.T) {
+	h, _ := NewPlus(7)
+
+	for i := 0; i < 129; i++ {
+		h.Add(fakeHash64(i << 39))
+	}
+	c := h.Clone()
+
+	n := h.Count()
+	if !h.sparse || len(h.tmpSet) == 0 {
+		t.Error("Count modified sketch")
+	}
+	if !reflect.DeepEqual(h, c) {
+		t.Error("Count modified sketch")
+	}
 
+	h.Flush()
 	if h.sparse {
 		t.Error("h should be converted to normal")
 	}
+	if n2 := h.Count(); n != n2 {
+		t.Error(n, n2)
+	}
+}
+
+func TestHLLPPConcurrentCount(t *testing.T) {
+	h, _ := NewPlus(10)
+	for i := 0; i < 50; i++ {
+		h.Add(fakeHash64(uint64(i) * 0x9e3779b97f4a7c15))
+	}
+	want := h.Count()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if n := h.Count(); n != want {
+					t.Error(n, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHLLPPAddHashes(t *testing.T) {
+	xs := make([]uint64, 0, 5000)
+	for i := uint64(0); i < 5000; i++ {
+		xs = append(xs, i*0x9e3779b97f4a7c15)
+	}
+
+	for _, n := range []int{0, 1, 10, 100, 5000} {
+		h, _ := NewPlus(8)
+		h2, _ := NewPlus(8)
+		for _, x := range xs[:n] {
+			h.Add(fakeHash64(x))
+		}
+		h2.AddHashes(xs[:n])
+
+		if h.sparse != h2.sparse {
+			t.Error(n, "representations differ")
+		}
+		if c1, c2 := h.Count(), h2.Count(); c1 != c2 {
+			t.Error(n, c1, c2)
+		}
+		if !reflect.DeepEqual(h.reg, h2.reg) {
+			t.Error(n, "AddHashes registers differ from Add")
+		}
+	}
+
+	h, _ := NewPlus(8)
+	h.AddHashes(xs)
+	if h.sparse {
+		t.Error("h should be converted to normal")
+	}
+}
+
+func TestHLLPPAddHashesChanged(t *testing.T) {
+	xs := make([]uint64, 0, 5000)
+	for i := uint64(0); i < 5000; i++ {
+		xs = append(xs, i*0x9e3779b97f4a7c15)
+	}
+
+	h, _ := NewPlus(8)
+	if h.AddHashesChanged(nil) {
+		t.Error("empty batch changed h")
+	}
+	if !h.AddHashesChanged(xs[:2]) {
+		t.Error("new items did not change h")
+	}
+	if h.AddHashesChanged(xs[:2]) {
+		t.Error("repeated items changed h")
+	}
+
+	// Flush the temporary set so repeated items are found in the sparse list.
+	h.AddHashesChanged(xs[2:10])
+	h.mergeSparse()
+	if h.AddHashesChanged(xs[:10]) {
+		t.Error("items in the sparse list changed h")
+	}
+	if !h.AddHashesChanged(xs[5:11]) {
+		t.Error("new item did not change h")
+	}
+	if !h.sparse {
+		t.Error("h should still be sparse")
+	}
+
+	if !h.AddHashesChanged(xs) {
+		t.Error("new items did not change h")
+	}
+	if h.sparse {
+		t.Error("h should be converted to normal")
+	}
+	if h.AddHashesChanged(xs) {
+		t.Error("repeated items changed normal h")
+	}
+
+	g, _ := NewPlus(8)
+	g.AddHashes(xs)
+	if !h.Equal(g) {
+		t.Error("AddHashesChanged differs from AddHashes")
+	}
+}
+
+func TestHLLPPMergeAll(t *testing.T) {
+	var sketches []*HyperLogLogPlus
+	for i := 0; i < 20; i++ {
+		s, _ := NewPlus(14)
+		for j := 0; j < 50*i; j++ {
+			s.Add(fakeHash64(uint64(i*1000+j) * 0x9e3779b97f4a7c15))
+		}
+		sketches = append(sketches, s)
+	}
+
+	for _, n := range []int{0, 1, 3, 10, 20} {
+		h, _ := NewPlus(14)
+		for _, s := range sketches[:n] {
+			h.Merge(s)
+		}
+
+		h2, _ := NewPlus(14)
+		if err := h2.MergeAll(sketches[:n]...); err != nil {
+			t.Error(err)
+		}
+
+		if c1, c2 := h.Count(), h2.Count(); c1 != c2 {
+			t.Error(n, c1, c2)
+		}
+		if h.sparse != h2.sparse {
+			t.Error(n, "representations differ")
+		}
+		if !reflect.DeepEqual(h.reg, h2.reg) {
+			t.Error(n, "MergeAll registers differ from Merge")
+		}
+	}
+}
+
+func TestHLLPPMergeAllOverlapping(t *testing.T) {
+	// 200 copies of the same 100 entries hold far more entries than there are
+	// registers, but their union is small.
+	s, _ := NewPlus(14)
+	for i := uint64(0); i < 100; i++ {
+		s.Add(fakeHash64(HashUint64(i)))
+	}
+	s.Flush()
+	var sketches []*HyperLogLogPlus
+	for i := 0; i < 200; i++ {
+		sketches = append(sketches, s.Clone())
+	}
+
+	h, _ := NewPlus(14)
+	for _, s := range sketches {
+		h.Merge(s)
+	}
+	h2, _ := NewPlus(14)
+	if err := h2.MergeAll(sketches...); err != nil {
+		t.Fatal(err)
+	}
+
+	if !h.sparse || !h2.sparse {
+		t.Error("merged sketches should stay sparse", h.sparse, h2.sparse)
+	}
+	if c1, c2 := h.Count(), h2.Count(); c1 != c2 || c1 != 100 {
+		t.Error(c1, c2)
+	}
+	if !h.Equal(h2) {
+		t.Error("MergeAll differs from Merge")
+	}
+}
+
+func TestHLLPPMergeAllManySparse(t *testing.T) {
+	// 400 sparse sketches of distinct items whose union needs the normal
+	// representation.
+	var sketches []*HyperLogLogPlus
+	for i := 0; i < 400; i++ {
+		s, _ := NewPlus(14)
+		for j := 0; j < 200; j++ {
+			s.Add(fakeHash64(HashUint64(uint64(i*200 + j))))
+		}
+		s.Flush()
+		sketches = append(sketches, s)
+	}
+
+	h, _ := NewPlus(14)
+	for _, s := range sketches {
+		h.Merge(s)
+	}
+
+	var before, after runtime.MemStats
+	h2, _ := NewPlus(14)
+	runtime.ReadMemStats(&before)
+	if err := h2.MergeAll(sketches...); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if h.sparse || h2.sparse {
+		t.Error("merged sketches should be normal", h.sparse, h2.sparse)
+	}
+	if !reflect.DeepEqual(h.reg, h2.reg) {
+		t.Error("MergeAll registers differ from Merge")
+	}
+	// The registers, at most m bytes of merged list and an iterator per input,
+	// but not the whole union.
+	if n := after.TotalAlloc - before.TotalAlloc; n > 8*uint64(h2.m) {
+		t.Errorf("MergeAll allocated %d bytes", n)
+	}
+}
+
+func TestHLLPPMergeAllNormal(t *testing.T) {
+	h, _ := NewPlus(16)
+	h.Add(fakeHash64(0x00010fffffffffff))
+
+	h2, _ := NewPlus(16)
+	h2.toNormal()
+	h2.Add(fakeHash64(0x00020fffffffffff))
+	h2.Add(fakeHash64(0xff03080000000000))
+
+	h3, _ := NewPlus(16)
+	h3.Add(fakeHash64(0x00030fffffffffff))
+
+	h4, _ := NewPlus(16)
+	if err := h4.MergeAll(h, h2, h3, h4); err != nil {
+		t.Error(err)
+	}
+
+	if h4.sparse {
+		t.Error("MergeAll should convert to normal")
+	}
+	n := h4.Count()
+	if n != 4 {
+		t.Error(n)
+	}
+	if h4.reg[0xff03] != 5 {
+		t.Error(h4.reg[0xff03])
+	}
+
+	if !h.sparse || !h3.sparse {
+		t.Error("MergeAll should not modify arguments")
+	}
+}
+
+func TestHLLPPMergeAllError(t *testing.T) {
+	h, _ := NewPlus(16)
+	h2, _ := NewPlus(16)
+	h3, _ := NewPlus(10)
+
+	err := h.MergeAll(h2, h3)
+	if err == nil {
+		t.Error("different precision should return error")
+	}
+}
+
+func TestHLLPPCountSharedIndex(t *testing.T) {
+	h, _ := NewPlus(16)
+
+	// Both hashes have the same index at precision pPrime, but record
+	// different ranks in the sparse list.
+	h.Add(fakeHash64(0x0001000000100000))
+	h.Add(fakeHash64(0x0001000000010000))
+
+	n := h.Count()
+	if n != 1 {
+		t.Error(n)
+	}
+	h.Flush()
+	if h.sparseList.Count != 2 {
+		t.Error(h.sparseList)
+	}
+}
+
+func TestHLLPPHighPrecision(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping high precision accuracy test in short mode")
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for _, p := range []uint8{19, 20, 22} {
+		for _, n := range []int{1000, 200000, 3000000} {
+			h, _ := NewPlus(p)
+			xs := make([]uint64, n)
+			for i := range xs {
+				xs[i] = r.Uint64()
+			}
+			h.AddHashes(xs)
+
+			// Allow four standard errors.
+			c := h.Count()
+			err := math.Abs(float64(c)-float64(n)) / float64(n)
+			if bound := 4 * 1.04 / math.Sqrt(float64(h.m)); err > bound {
+				t.Errorf("p=%d n=%d: count %d, error %f > %f", p, n, c, err, bound)
+			}
+		}
+	}
+}
+
+func TestHLLPPHighPrecisionNoSparse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping high precision accuracy test in short mode")
+	}
+
+	r := rand.New(rand.NewSource(2))
+	h, _ := NewPlus(20)
+	h.toNormal()
+
+	if n := h.Count(); n != 0 {
+		t.Error(n)
+	}
+
+	var added int
+	for _, n := range []int{10, 1000, 100000, 1000000, 5000000} {
+		xs := make([]uint64, n-added)
+		for i := range xs {
+			xs[i] = r.Uint64()
+		}
+		h.AddHashes(xs)
+		added = n
+
+		c := h.Count()
+		err := math.Abs(float64(c)-float64(n)) / float64(n)
+		if bound := 4 * 1.04 / math.Sqrt(float64(h.m)); err > bound {
+			t.Errorf("n=%d: count %d, error %f > %f", n, c, err, bound)
+		}
+	}
+}
+
+func TestHLLPPClone(t *testing.T) {
+	h, _ := NewPlus(10)
+	for i := 0; i < 100; i++ {
+		h.Add(fakeHash64(uint64(i) * 0x9e3779b97f4a7c15))
+	}
+	h.mergeSparse()
+	h.Add(fakeHash64(0xdeadbeef00000000))
+
+	c := h.Clone()
+	if !h.Equal(c) || !c.Equal(h) {
+		t.Error("clone differs")
+	}
+	n, tmp := h.sparseList.Count, len(h.tmpSet)
+
+	for i := 0; i < 5000; i++ {
+		c.Add(fakeHash64(uint64(i+1000) * 0x9e3779b97f4a7c15))
+	}
+	if c.sparse {
+		t.Error("clone should have converted to normal")
+	}
+	if !h.sparse || len(h.tmpSet) != tmp || h.sparseList.Count != n {
+		t.Error("adding to clone changed original")
+	}
+	if h.Equal(c) {
+		t.Error("sketches should differ")
+	}
+	if c2 := h.Clone(); c2.Count() != h.Count() {
+		t.Error(c2.Count(), h.Count())
+	}
+
+	d := c.Clone()
+	d.reg[0] = 63
+	if c.reg[0] == 63 {
+		t.Error("dense clone shares registers")
+	}
+}
+
+func TestHLLPPEqual(t *testing.T) {
+	hashes := make([]uint64, 300)
+	for i := range hashes {
+		hashes[i] = uint64(i+1) * 0x9e3779b97f4a7c15
+	}
+
+	// Same items, one with everything in tmpSet and one fully merged.
+	h1, _ := NewPlus(10)
+	h2, _ := NewPlus(10)
+	for _, x := range hashes[:50] {
+		h1.Add(fakeHash64(x))
+		h2.Add(fakeHash64(x))
+	}
+	h2.mergeSparse()
+	if len(h1.tmpSet) == 0 || len(h2.tmpSet) != 0 {
+		t.Fatal("unexpected representations")
+	}
+	if !h1.Equal(h2) || !h2.Equal(h1) {
+		t.Error("tmpSet and sparse list should be equal")
+	}
+	if len(h1.tmpSet) == 0 {
+		t.Error("Equal modified sketch")
+	}
+
+	h2.Add(fakeHash64(hashes[50]))
+	if h1.Equal(h2) {
+		t.Error("sketches should differ")
+	}
+
+	// Same items, one sparse and one dense. Their counts differ, since the
+	// sparse one still uses linear counting at precision pPrime.
+	h3, _ := NewPlus(10)
+	h4, _ := NewPlus(10)
+	for _, x := range hashes {
+		h3.Add(fakeHash64(x))
+		h4.Add(fakeHash64(x))
+	}
+	h4.toNormal()
+	if !h3.sparse || h4.sparse {
+		t.Fatal("unexpected representations")
+	}
+	if h3.Equal(h4) || h4.Equal(h3) {
+		t.Error("sparse and dense should differ")
+	}
+	if !h3.sparse {
+		t.Error("Equal modified sketch")
+	}
+
+	// A sparse sketch whose temporary set is too large to stay sparse once
+	// merged is compared by its registers.
+	h7, _ := NewPlus(10)
+	for i := 0; i < 1000; i++ {
+		h7.tmpSet.Add(h7.encodeHash(HashUint64(uint64(i))))
+	}
+	h8 := h7.Clone()
+	h8.mergeSparseAndToNormal()
+	if !h7.sparse || h8.sparse {
+		t.Fatal("unexpected representations")
+	}
+	if !h7.Equal(h8) || !h8.Equal(h7) {
+		t.Error("sparse sketch too large to stay sparse should equal dense")
+	}
+
+	// Different sparse entries for the same register and rank give the same
+	// registers, but different counts.
+	x := uint64(5)<<54 | 1<<53
+	a, _ := NewPlus(10)
+	c, _ := NewPlus(10)
+	a.Add(fakeHash64(x))
+	a.Add(fakeHash64(x | 1<<39))
+	c.Add(fakeHash64(x))
+	if a.Equal(c) || c.Equal(a) {
+		t.Error("sparse sketches with different entries should differ")
+	}
+
+	sketches := []*HyperLogLogPlus{h1, h2, h3, h4, h7, h8, a, c}
+	for i, s1 := range sketches {
+		for j, s2 := range sketches {
+			if s1.Equal(s2) && s1.Count() != s2.Count() {
+				t.Errorf("sketches %d and %d are equal but count %d and %d", i, j, s1.Count(), s2.Count())
+			}
+		}
+	}
+
+	h5, _ := NewPlus(11)
+	if h5.Equal(&HyperLogLogPlus{p: 10}) {
+		t.Error("different precisions should differ")
+	}
+	h6, _ := NewPlus(11)
+	if !h5.Equal(h6) {
+		t.Error("empty sketches should be equal")
+	}
 }
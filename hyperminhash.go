@@ -0,0 +1,278 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Each HyperMinHash register holds a 6-bit rank above a 10-bit mantissa.
+const minHashMantissaBits = 10
+
+// HyperMinHash is a HyperLogLog whose registers also keep a few bits of the
+// hash that set them, as described in Yun William Yu and Griffin M. Weber,
+// "HyperMinHash: MinHash in LogLog space" (2017):
+// https://arxiv.org/abs/1710.08436
+//
+// Registers that hold the same value in two sketches most likely saw the same
+// item, so besides the cardinality of each sketch and of their union,
+// HyperMinHash estimates their Jaccard index and intersection much more
+// accurately than inclusion-exclusion on HyperLogLog sketches can, especially
+// for small intersections. Each register takes two bytes.
+//
+// The lowest 10 bits of each hash form the mantissa, the top p bits the register
+// index, and the bits in between the rank.
+type HyperMinHash struct {
+	reg []uint16
+	m   uint32
+	p   uint8
+}
+
+// NewMinHash returns a new initialized HyperMinHash.
+func NewMinHash(precision uint8) (*HyperMinHash, error) {
+	if precision > 18 || precision < 4 {
+		return nil, errors.New("precision must be between 4 and 18")
+	}
+
+	h := &HyperMinHash{}
+	h.p = precision
+	h.m = 1 << precision
+	h.reg = make([]uint16, h.m)
+	return h, nil
+}
+
+// Clear sets HyperMinHash h back to its initial state.
+func (h *HyperMinHash) Clear() {
+	h.reg = make([]uint16, h.m)
+}
+
+// Clone returns a copy of HyperMinHash h that shares no state with it.
+func (h *HyperMinHash) Clone() *HyperMinHash {
+	c := *h
+	c.reg = append([]uint16(nil), h.reg...)
+	return &c
+}
+
+// The number of hash bits that determine the rank, which is at most q+1.
+func (h *HyperMinHash) q() uint8 {
+	return 64 - h.p - minHashMantissaBits
+}
+
+// Add adds a new item to HyperMinHash h.
+func (h *HyperMinHash) Add(item Hash64) {
+	h.addHash(item.Sum64())
+}
+
+func (h *HyperMinHash) addHash(x uint64) {
+	p := h.p
+	i := eb64(x, 64, 64-p)                      // {x63,...,x64-p}
+	rest := eb64(x, 64-p, minHashMantissaBits)  // {x63-p,...,x10}
+	mantissa := eb64(x, minHashMantissaBits, 0) // {x9,...,x0}
+	w := rest<<(p+minHashMantissaBits) | 1<<(p+minHashMantissaBits-1)
+
+	zeroBits := clz64(w) + 1
+	v := uint16(zeroBits)<<minHashMantissaBits | uint16(mantissa)
+	if v > h.reg[i] {
+		h.reg[i] = v
+	}
+}
+
+// AddHashes adds a batch of already hashed items to HyperMinHash h.
+func (h *HyperMinHash) AddHashes(xs []uint64) {
+	for _, x := range xs {
+		h.addHash(x)
+	}
+}
+
+// Merge takes another HyperMinHash and combines it with HyperMinHash h, so that
+// h describes the union of both.
+func (h *HyperMinHash) Merge(other *HyperMinHash) error {
+	if h.p != other.p {
+		return errors.New("precisions must be equal")
+	}
+
+	for i, v := range other.reg {
+		if v > h.reg[i] {
+			h.reg[i] = v
+		}
+	}
+	return nil
+}
+
+// Returns the rank held in each register of reg.
+func (h *HyperMinHash) ranks(reg []uint16) []uint8 {
+	r := make([]uint8, len(reg))
+	for i, v := range reg {
+		r[i] = uint8(v >> minHashMantissaBits)
+	}
+	return r
+}
+
+// Count returns the cardinality estimate.
+func (h *HyperMinHash) Count() uint64 {
+	return uint64(ertlEstimate(h.ranks(h.reg), h.q()))
+}
+
+// Union returns the estimated cardinality of the union of HyperMinHash h and
+// other. Neither sketch is modified.
+func (h *HyperMinHash) Union(other *HyperMinHash) (uint64, error) {
+	u := h.Clone()
+	if err := u.Merge(other); err != nil {
+		return 0, err
+	}
+	return u.Count(), nil
+}
+
+// Jaccard returns the estimated Jaccard index of HyperMinHash h and other: the
+// size of their intersection divided by the size of their union. It is the
+// fraction of registers set in either sketch that hold the same value in both,
+// less the fraction expected to match by chance for sets of their sizes with
+// nothing in common. It returns 0 if both sketches are empty.
+func (h *HyperMinHash) Jaccard(other *HyperMinHash) (float64, error) {
+	if h.p != other.p {
+		return 0, errors.New("precisions must be equal")
+	}
+
+	var matches, union uint32
+	for i, v := range h.reg {
+		w := other.reg[i]
+		if v == 0 && w == 0 {
+			continue
+		}
+		union++
+		if v == w {
+			matches++
+		}
+	}
+	if matches == 0 {
+		return 0, nil
+	}
+
+	expected := h.expectedCollisions(float64(h.Count()), float64(other.Count()))
+	j := (float64(matches) - expected) / float64(union)
+	if j < 0 {
+		return 0, nil
+	}
+	return j, nil
+}
+
+// Intersection returns the estimated cardinality of the intersection of
+// HyperMinHash h and other, the product of their Jaccard index and the size of
+// their union.
+func (h *HyperMinHash) Intersection(other *HyperMinHash) (uint64, error) {
+	j, err := h.Jaccard(other)
+	if err != nil {
+		return 0, err
+	}
+	u, _ := h.Union(other)
+	return uint64(math.Round(j * float64(u))), nil
+}
+
+// Returns the expected number of registers that hold the same non-zero value in
+// two sketches of disjoint sets with n and k items.
+//
+// An item lands in a given register with probability 1/m, and its value there
+// is at most v with probability F(v), so the register is at most v with
+// probability (1-(1-F(v))/m)^n. The chance of a collision is the sum over all
+// values of the product of the probabilities that each register holds it.
+func (h *HyperMinHash) expectedCollisions(n, k float64) float64 {
+	m := float64(h.m)
+	q := int(h.q())
+	mantissas := float64(uint32(1) << minHashMantissaBits)
+
+	atMost := func(f, n float64) float64 {
+		return math.Exp(n * math.Log1p(-(1-f)/m))
+	}
+
+	var sum float64
+	lastN, lastK := atMost(0, n), atMost(0, k)
+	for r := 1; r <= q+1; r++ {
+		// The probabilities of a rank below r and of a rank of exactly r.
+		below := 1 - math.Ldexp(1, 1-r)
+		exact := math.Ldexp(1, -r)
+		if r == q+1 {
+			exact = math.Ldexp(1, -q)
+		}
+
+		for j := 1.0; j <= mantissas; j++ {
+			f := below + exact*j/mantissas
+			gn, gk := atMost(f, n), atMost(f, k)
+			sum += (gn - lastN) * (gk - lastK)
+			lastN, lastK = gn, gk
+		}
+	}
+	return m * sum
+}
+
+// Checks that every register holds a possible value.
+func (h *HyperMinHash) validate() error {
+	if uint32(len(h.reg)) != h.m {
+		return errors.New("invalid number of registers")
+	}
+	for _, v := range h.reg {
+		r := v >> minHashMantissaBits
+		if r > uint16(h.q())+1 || (r == 0 && v != 0) {
+			return errors.New("invalid register value")
+		}
+	}
+	return nil
+}
+
+// WriteTo writes HyperMinHash h to w in the format of MarshalBinary.
+func (h *HyperMinHash) WriteTo(w io.Writer) (int64, error) {
+	b := make([]byte, 2*len(h.reg))
+	for i, v := range h.reg {
+		binary.LittleEndian.PutUint16(b[2*i:], v)
+	}
+	return writeSketch(w, kindMinHash, h.p, b)
+}
+
+// ReadFrom reads a HyperMinHash written by WriteTo or MarshalBinary from r into
+// h. It reads exactly one sketch and never reads past its end, so several
+// sketches can be read one after another from the same reader. It returns
+// io.EOF if r is already at its end.
+func (h *HyperMinHash) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	kind, p, err := readHeader(cr)
+	if err != nil {
+		return cr.n, err
+	}
+	if kind != kindMinHash {
+		return cr.n, errEncodingKind
+	}
+
+	if p > 18 {
+		return cr.n, errors.New("precision must be between 4 and 18")
+	}
+
+	g := HyperMinHash{p: p, m: 1 << p}
+	b, err := readRegisters(cr, 2*g.m)
+	if err != nil {
+		return cr.n, err
+	}
+	g.reg = make([]uint16, g.m)
+	for i := range g.reg {
+		g.reg[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	if err := g.validate(); err != nil {
+		return cr.n, err
+	}
+	*h = g
+	return cr.n, nil
+}
+
+// MarshalBinary encodes HyperMinHash h in a stable binary format.
+func (h *HyperMinHash) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 3+2*len(h.reg)))
+	if _, err := h.WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a HyperMinHash encoded with MarshalBinary into h.
+func (h *HyperMinHash) UnmarshalBinary(b []byte) error {
+	return unmarshalBinary(b, h.ReadFrom)
+}
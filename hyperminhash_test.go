@@ -0,0 +1,207 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+// Returns a HyperMinHash of precision p holding the integers in [lo, hi).
+func minHashRange(p uint8, lo, hi uint64) *HyperMinHash {
+	h, _ := NewMinHash(p)
+	for i := lo; i < hi; i++ {
+		h.Add(fakeHash64(HashUint64(i)))
+	}
+	return h
+}
+
+func TestMinHashNew(t *testing.T) {
+	for _, p := range []uint8{3, 19} {
+		if _, err := NewMinHash(p); err == nil {
+			t.Error("expected error for precision", p)
+		}
+	}
+}
+
+func TestMinHashAdd(t *testing.T) {
+	h, _ := NewMinHash(8)
+
+	// Index 0x01, rank 3 and mantissa 0x2a5.
+	h.Add(fakeHash64(0x0120_0000_0000_02a5))
+	if v := h.reg[1]; v != 3<<10|0x2a5 {
+		t.Errorf("%#x", v)
+	}
+
+	// Same rank with a lower mantissa does not replace it.
+	h.Add(fakeHash64(0x0120_0000_0000_0001))
+	if v := h.reg[1]; v != 3<<10|0x2a5 {
+		t.Errorf("%#x", v)
+	}
+
+	// All rank bits zero gives the largest rank, 64-8-10+1.
+	h.Add(fakeHash64(0x0200_0000_0000_0000))
+	if v := h.reg[2]; v != 47<<10 {
+		t.Errorf("%#x", v)
+	}
+}
+
+func TestMinHashCount(t *testing.T) {
+	for _, n := range []uint64{0, 1, 100, 10000, 1000000} {
+		h := minHashRange(14, 0, n)
+		got := float64(h.Count())
+		if math.Abs(got-float64(n)) > 0.03*float64(n)+1 {
+			t.Errorf("n = %d: got %v", n, got)
+		}
+	}
+}
+
+func TestMinHashJaccard(t *testing.T) {
+	for _, tc := range []struct {
+		aLo, aHi, bLo, bHi uint64
+		want               float64
+		tol                float64
+	}{
+		{0, 10000, 0, 10000, 1, 0.001},
+		{0, 10000, 5000, 15000, 1.0 / 3, 0.02},
+		{0, 100000, 99000, 199000, 1000.0 / 199000, 0.002},
+		{0, 100000, 100000, 200000, 0, 0.002},
+		{0, 1000, 0, 1000000, 0.001, 0.001},
+	} {
+		a := minHashRange(14, tc.aLo, tc.aHi)
+		b := minHashRange(14, tc.bLo, tc.bHi)
+		j, err := a.Jaccard(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(j-tc.want) > tc.tol {
+			t.Errorf("%+v: got %v", tc, j)
+		}
+	}
+}
+
+func TestMinHashIntersection(t *testing.T) {
+	a := minHashRange(14, 0, 100000)
+	b := minHashRange(14, 99000, 199000)
+
+	u, err := a.Union(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(u)-199000) > 0.02*199000 {
+		t.Error(u)
+	}
+
+	n, err := a.Intersection(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 600 || n > 1400 {
+		t.Error(n)
+	}
+
+	empty, _ := NewMinHash(14)
+	if n, _ := empty.Intersection(empty); n != 0 {
+		t.Error(n)
+	}
+	if n, _ := a.Intersection(empty); n != 0 {
+		t.Error(n)
+	}
+}
+
+func TestMinHashMerge(t *testing.T) {
+	a := minHashRange(10, 0, 3000)
+	b := minHashRange(10, 2000, 5000)
+	want := minHashRange(10, 0, 5000)
+
+	if u, _ := a.Union(b); u != want.Count() {
+		t.Error(u, want.Count())
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if !equalUint16s(a.reg, want.reg) {
+		t.Error("merged registers differ")
+	}
+
+	other, _ := NewMinHash(11)
+	if err := a.Merge(other); err == nil {
+		t.Error("expected precision error")
+	}
+	if _, err := a.Jaccard(other); err == nil {
+		t.Error("expected precision error")
+	}
+	if _, err := a.Union(other); err == nil {
+		t.Error("expected precision error")
+	}
+}
+
+func equalUint16s(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMinHashBinary(t *testing.T) {
+	h := minHashRange(6, 0, 1000)
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 3+2*64 || !bytes.Equal(b[:3], []byte{1, kindMinHash, 6}) {
+		t.Fatal(b[:3], len(b))
+	}
+
+	var g HyperMinHash
+	if err := g.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if g.p != 6 || !equalUint16s(g.reg, h.reg) {
+		t.Error("decoded sketch differs")
+	}
+
+	// Several sketches can be read from one stream.
+	var buf bytes.Buffer
+	h.WriteTo(&buf)
+	h.WriteTo(&buf)
+	for i := 0; i < 2; i++ {
+		if _, err := g.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := g.ReadFrom(&buf); err != io.EOF {
+		t.Error(err)
+	}
+
+	if err := g.UnmarshalBinary(b[:len(b)-1]); err != io.ErrUnexpectedEOF {
+		t.Error(err)
+	}
+	if err := g.UnmarshalBinary(append(b, 0)); err == nil {
+		t.Error("expected trailing data error")
+	}
+
+	bad := append([]byte(nil), b...)
+	bad[3], bad[4] = 0, 63<<2
+	if err := g.UnmarshalBinary(bad); err == nil {
+		t.Error("expected invalid register error")
+	}
+	bad[3], bad[4] = 1, 0
+	if err := g.UnmarshalBinary(bad); err == nil {
+		t.Error("expected invalid register error")
+	}
+
+	plus, _ := NewPlus(6)
+	pb, _ := plus.MarshalBinary()
+	if err := g.UnmarshalBinary(pb); err != errEncodingKind {
+		t.Error(err)
+	}
+	if err := plus.UnmarshalBinary(b); err != errEncodingKind {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,64 @@
+package hyperloglog
+
+// Info describes the internal state of a sketch, for monitoring and debugging.
+type Info struct {
+	// Algorithm is "HyperLogLog", "HyperLogLog64" for a HyperLogLog created
+	// with New64, or "HyperLogLog++".
+	Algorithm string `json:"algorithm"`
+
+	Precision uint8 `json:"precision"`
+
+	// Representation is "sparse" or "normal".
+	Representation string `json:"representation"`
+
+	// Registers is the number of registers, m. In the sparse representation
+	// they have not been allocated yet.
+	Registers int `json:"registers"`
+
+	// SparseEntries and SparseBytes are the number of entries in the sparse
+	// list and its encoded size, and TmpSetEntries is the number of entries
+	// waiting to be merged into it.
+	SparseEntries int `json:"sparse_entries"`
+	SparseBytes   int `json:"sparse_bytes"`
+	TmpSetEntries int `json:"tmp_set_entries"`
+
+	// Bytes approximates the memory used by the sketch.
+	Bytes int `json:"bytes"`
+}
+
+// The approximate memory used by each entry of a set, including map overhead.
+const setEntryBytes = 8
+
+// Info describes the internal state of HyperLogLog h.
+func (h *HyperLogLog) Info() Info {
+	alg := "HyperLogLog"
+	if h.wide {
+		alg = "HyperLogLog64"
+	}
+	return Info{
+		Algorithm:      alg,
+		Precision:      h.p,
+		Representation: "normal",
+		Registers:      int(h.m),
+		Bytes:          cap(h.reg),
+	}
+}
+
+// Info describes the internal state of HyperLogLogPlus h.
+func (h *HyperLogLogPlus) Info() Info {
+	info := Info{
+		Algorithm:      "HyperLogLog++",
+		Precision:      h.p,
+		Representation: "normal",
+		Registers:      int(h.m),
+		Bytes:          cap(h.reg),
+	}
+	if h.sparse {
+		info.Representation = "sparse"
+		info.SparseEntries = int(h.sparseList.Count)
+		info.SparseBytes = h.sparseList.Len()
+		info.TmpSetEntries = len(h.tmpSet)
+		info.Bytes += cap(h.sparseList.b) + setEntryBytes*len(h.tmpSet)
+	}
+	return info
+}
@@ -0,0 +1,43 @@
+package hyperloglog
+
+import "testing"
+
+func TestHLLInfo(t *testing.T) {
+	h, _ := New(8)
+	want := Info{Algorithm: "HyperLogLog", Precision: 8, Representation: "normal", Registers: 256, Bytes: 256}
+	if info := h.Info(); info != want {
+		t.Error(info)
+	}
+
+	h, _ = New64(4)
+	if info := h.Info(); info.Algorithm != "HyperLogLog64" || info.Registers != 16 {
+		t.Error(info)
+	}
+}
+
+func TestHLLPPInfo(t *testing.T) {
+	h, _ := NewPlus(10)
+	for i := 0; i < 20; i++ {
+		h.Add(fakeHash64(uint64(i+1) * 0x9e3779b97f4a7c15))
+	}
+	h.Flush()
+	h.Add(fakeHash64(0xdeadbeef00000000))
+
+	info := h.Info()
+	if info.Algorithm != "HyperLogLog++" || info.Precision != 10 || info.Representation != "sparse" || info.Registers != 1024 {
+		t.Error(info)
+	}
+	if info.SparseEntries != 20 || info.SparseBytes != h.sparseList.Len() || info.TmpSetEntries != 1 {
+		t.Error(info)
+	}
+	if info.Bytes < info.SparseBytes || info.Bytes > 2048 {
+		t.Error(info)
+	}
+
+	h.Flush()
+	h.toNormal()
+	want := Info{Algorithm: "HyperLogLog++", Precision: 10, Representation: "normal", Registers: 1024, Bytes: 1024}
+	if info := h.Info(); info != want {
+		t.Error(info)
+	}
+}
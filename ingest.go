@@ -0,0 +1,209 @@
+package hyperloglog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// IngestOptions configure Ingest. The zero value counts distinct lines.
+type IngestOptions struct {
+	// Precision of the resulting sketch. Defaults to 14.
+	Precision uint8
+
+	// Split divides the input into tokens. Defaults to bufio.ScanLines. Use
+	// bufio.ScanWords for whitespace separated tokens, ScanFixed for fixed
+	// width records or ScanNUL for NUL terminated records.
+	Split bufio.SplitFunc
+
+	// MaxTokenSize is the longest token Split may return. Defaults to
+	// bufio.MaxScanTokenSize.
+	MaxTokenSize int
+
+	// Hash hashes each token. Defaults to HashBytes.
+	Hash func([]byte) uint64
+
+	// Workers is the number of goroutines hashing tokens. Defaults to
+	// GOMAXPROCS.
+	Workers int
+
+	// BatchSize is the number of tokens handed to a worker at once. Defaults
+	// to 4096.
+	BatchSize int
+
+	// Progress, if not nil, is called after each batch and once at the end
+	// with the total bytes read and tokens found so far. It is called from
+	// the goroutine that called Ingest.
+	Progress func(IngestProgress)
+}
+
+// IngestProgress reports how far Ingest has read.
+type IngestProgress struct {
+	Bytes  int64
+	Tokens int64
+}
+
+// A batch of tokens stored back to back in data.
+type ingestBatch struct {
+	data []byte
+	ends []int
+}
+
+// Ingest reads tokens from r and returns a HyperLogLogPlus counting the
+// distinct tokens. Tokens are hashed by worker goroutines with their own
+// sketches, which are merged once r is exhausted.
+//
+// Ingest stops and returns ctx.Err() when ctx is done. It checks ctx between
+// batches, so a Read on r that blocks is not interrupted.
+func Ingest(ctx context.Context, r io.Reader, opts *IngestOptions) (*HyperLogLogPlus, error) {
+	var o IngestOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Precision == 0 {
+		o.Precision = 14
+	}
+	if o.Split == nil {
+		o.Split = bufio.ScanLines
+	}
+	if o.MaxTokenSize <= 0 {
+		o.MaxTokenSize = bufio.MaxScanTokenSize
+	}
+	if o.Hash == nil {
+		o.Hash = HashBytes
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 4096
+	}
+
+	sketches := make([]*HyperLogLogPlus, o.Workers)
+	for i := range sketches {
+		h, err := NewPlus(o.Precision)
+		if err != nil {
+			return nil, err
+		}
+		sketches[i] = h
+	}
+
+	// Batches cycle between the reader and the workers through free, so at
+	// most two per worker are ever allocated.
+	work := make(chan *ingestBatch, o.Workers)
+	free := make(chan *ingestBatch, 2*o.Workers)
+	for i := 0; i < 2*o.Workers; i++ {
+		free <- &ingestBatch{ends: make([]int, 0, o.BatchSize)}
+	}
+
+	var wg sync.WaitGroup
+	for _, h := range sketches {
+		wg.Add(1)
+		go func(h *HyperLogLogPlus) {
+			defer wg.Done()
+			hashes := make([]uint64, 0, o.BatchSize)
+			for b := range work {
+				hashes = hashes[:0]
+				start := 0
+				for _, end := range b.ends {
+					hashes = append(hashes, o.Hash(b.data[start:end]))
+					start = end
+				}
+				h.AddHashes(hashes)
+				b.data, b.ends = b.data[:0], b.ends[:0]
+				free <- b
+			}
+		}(h)
+	}
+
+	cr := &countingReader{r: r}
+	sc := bufio.NewScanner(cr)
+	sc.Buffer(nil, o.MaxTokenSize)
+	sc.Split(o.Split)
+
+	var tokens int64
+	err := func() error {
+		var b *ingestBatch
+		for {
+			if b == nil {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				select {
+				case b = <-free:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			more := sc.Scan()
+			if more {
+				b.data = append(b.data, sc.Bytes()...)
+				b.ends = append(b.ends, len(b.data))
+				tokens++
+			}
+			if len(b.ends) == o.BatchSize || (!more && len(b.ends) > 0) {
+				select {
+				case work <- b:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				b = nil
+				if o.Progress != nil {
+					o.Progress(IngestProgress{cr.n, tokens})
+				}
+			}
+			if !more {
+				return sc.Err()
+			}
+		}
+	}()
+	close(work)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if o.Progress != nil {
+		o.Progress(IngestProgress{cr.n, tokens})
+	}
+
+	h := sketches[0]
+	if err := h.MergeAll(sketches[1:]...); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ScanFixed returns a bufio.SplitFunc that splits its input into records of n
+// bytes. A shorter final record is returned as is. It panics if n is not
+// positive.
+func ScanFixed(n int) bufio.SplitFunc {
+	if n <= 0 {
+		panic("hyperloglog: non-positive record size")
+	}
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if len(data) >= n {
+			return n, data[:n], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// ScanNUL is a bufio.SplitFunc that splits its input into NUL terminated
+// records, such as the output of find -print0. The final record need not be
+// terminated.
+func ScanNUL(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
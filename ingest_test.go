@@ -0,0 +1,160 @@
+package hyperloglog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIngestSplitters(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		split bufio.SplitFunc
+		want  []string
+	}{
+		{"lines", "a\nb\r\na\n\nc", nil, []string{"a", "b", "a", "", "c"}},
+		{"words", "  a b\t\na  c\n", bufio.ScanWords, []string{"a", "b", "a", "c"}},
+		{"fixed", "abcdefabcdeg", ScanFixed(3), []string{"abc", "def", "abc", "deg"}},
+		{"fixed short", "abcdefg", ScanFixed(3), []string{"abc", "def", "g"}},
+		{"nul", "a\x00b\x00\x00a", ScanNUL, []string{"a", "b", "", "a"}},
+	} {
+		var got []string
+		sc := bufio.NewScanner(strings.NewReader(tc.input))
+		if tc.split != nil {
+			sc.Split(tc.split)
+		}
+		for sc.Scan() {
+			got = append(got, sc.Text())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+
+		h, err := Ingest(context.Background(), strings.NewReader(tc.input), &IngestOptions{
+			Split:     tc.split,
+			Workers:   3,
+			BatchSize: 1,
+		})
+		if err != nil {
+			t.Fatal(tc.name, err)
+		}
+		seen := map[string]bool{}
+		for _, s := range tc.want {
+			seen[s] = true
+		}
+		if n := h.Count(); n != uint64(len(seen)) {
+			t.Errorf("%s: count %d, want %d", tc.name, n, len(seen))
+		}
+	}
+}
+
+func TestIngestMatchesSequential(t *testing.T) {
+	var b strings.Builder
+	want, _ := NewPlus(12)
+	for i := 0; i < 50000; i++ {
+		s := fmt.Sprint("item-", i%30000)
+		b.WriteString(s)
+		b.WriteByte('\n')
+		want.AddHashes([]uint64{HashString(s)})
+	}
+
+	for _, workers := range []int{0, 1, 4} {
+		h, err := Ingest(context.Background(), strings.NewReader(b.String()), &IngestOptions{
+			Precision: 12,
+			Workers:   workers,
+			BatchSize: 1000,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !h.Equal(want) {
+			t.Errorf("workers %d: sketch differs from sequential", workers)
+		}
+	}
+}
+
+func TestIngestProgress(t *testing.T) {
+	input := strings.Repeat("x\n", 10000)
+	var calls []IngestProgress
+	_, err := Ingest(context.Background(), strings.NewReader(input), &IngestOptions{
+		BatchSize: 1000,
+		Progress:  func(p IngestProgress) { calls = append(calls, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 11 {
+		t.Fatalf("got %d progress calls, want 11", len(calls))
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i].Bytes < calls[i-1].Bytes || calls[i].Tokens < calls[i-1].Tokens {
+			t.Errorf("progress went backwards: %v then %v", calls[i-1], calls[i])
+		}
+	}
+	if last := calls[len(calls)-1]; last != (IngestProgress{int64(len(input)), 10000}) {
+		t.Error(last)
+	}
+}
+
+// endless returns the same line forever.
+type endless struct{}
+
+func (endless) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = "ab\n"[i%3]
+	}
+	return len(b), nil
+}
+
+func TestIngestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Ingest(ctx, strings.NewReader("a\n"), nil); err != context.Canceled {
+		t.Error(err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	_, err := Ingest(ctx, endless{}, &IngestOptions{
+		Progress: func(p IngestProgress) {
+			if p.Tokens > 100000 {
+				cancel()
+			}
+		},
+	})
+	if err != context.Canceled {
+		t.Error(err)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestIngestErrors(t *testing.T) {
+	if _, err := Ingest(context.Background(), errReader{}, nil); err == nil || err.Error() != "read failed" {
+		t.Error(err)
+	}
+
+	long := strings.Repeat("x", 100)
+	_, err := Ingest(context.Background(), strings.NewReader(long), &IngestOptions{MaxTokenSize: 10})
+	if err != bufio.ErrTooLong {
+		t.Error(err)
+	}
+
+	if _, err := Ingest(context.Background(), strings.NewReader(""), &IngestOptions{Precision: 30}); err == nil {
+		t.Error("expected precision error")
+	}
+
+	h, err := Ingest(context.Background(), io.LimitReader(endless{}, 0), nil)
+	if err != nil || h.Count() != 0 {
+		t.Error(h, err)
+	}
+}
@@ -0,0 +1,25 @@
+package hyperloglog
+
+// The register kernels used by Merge and Count. maxRegisters and
+// sumRegisters are selected at build time: the default build uses the
+// word-at-a-time versions in kernels_swar.go, and building with the purego
+// tag uses the scalar versions below. Both must produce identical results.
+
+// Sets each register in dst to the maximum of itself and the same register
+// in src.
+func maxRegistersGeneric(dst, src []uint8) {
+	for i, v := range src {
+		if v > dst[i] {
+			dst[i] = v
+		}
+	}
+}
+
+// Returns the sum of 2^-r over all registers r in s.
+func sumRegistersGeneric(s []uint8) float64 {
+	sum := 0.0
+	for _, val := range s {
+		sum += 1.0 / float64(uint64(1)<<val)
+	}
+	return sum
+}
@@ -0,0 +1,11 @@
+//go:build purego
+
+package hyperloglog
+
+func maxRegisters(dst, src []uint8) {
+	maxRegistersGeneric(dst, src)
+}
+
+func sumRegisters(s []uint8) float64 {
+	return sumRegistersGeneric(s)
+}
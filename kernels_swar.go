@@ -0,0 +1,52 @@
+//go:build !purego
+
+package hyperloglog
+
+import "encoding/binary"
+
+const highBits = 0x8080808080808080
+
+// invPow2[r] is 2^-r, computed the same way as sumRegistersGeneric so that
+// table lookups give bit for bit the same sum.
+var invPow2 [256]float64
+
+func init() {
+	for r := range invPow2 {
+		invPow2[r] = 1.0 / float64(uint64(1)<<uint(r))
+	}
+}
+
+// Sets each register in dst to the maximum of itself and the same register
+// in src, eight registers at a time.
+func maxRegisters(dst, src []uint8) {
+	n := len(src) &^ 7
+	dst = dst[:len(src)]
+	for i := 0; i < n; i += 8 {
+		a := binary.LittleEndian.Uint64(dst[i:])
+		b := binary.LittleEndian.Uint64(src[i:])
+		binary.LittleEndian.PutUint64(dst[i:], max8(a, b))
+	}
+	maxRegistersGeneric(dst[n:], src[n:])
+}
+
+// Returns the bytewise unsigned maximum of a and b.
+func max8(a, b uint64) uint64 {
+	// The high bit of each byte of lo is set where the low seven bits of a are
+	// at least those of b. Setting the high bits of a first keeps the
+	// subtraction from borrowing across bytes.
+	lo := ((a | highBits) - (b &^ highBits)) & highBits
+
+	// If the high bits differ, the byte with the high bit set is larger.
+	ge := (a&^b | ^(a^b)&lo) & highBits
+	mask := (ge >> 7) * 0xff
+	return a&mask | b&^mask
+}
+
+// Returns the sum of 2^-r over all registers r in s.
+func sumRegisters(s []uint8) float64 {
+	sum := 0.0
+	for _, val := range s {
+		sum += invPow2[val]
+	}
+	return sum
+}
@@ -0,0 +1,106 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randRegisters(r *rand.Rand, n int, max int) []uint8 {
+	s := make([]uint8, n)
+	for i := range s {
+		s[i] = uint8(r.Intn(max + 1))
+	}
+	return s
+}
+
+func checkMaxRegisters(t *testing.T, a, b []uint8) {
+	want := append([]uint8(nil), a...)
+	maxRegistersGeneric(want, b)
+
+	got := append([]uint8(nil), a...)
+	maxRegisters(got, b)
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("maxRegisters(%v, %v) = %v, want %v", a, b, got, want)
+	}
+}
+
+func checkSumRegisters(t *testing.T, s []uint8) {
+	want, got := sumRegistersGeneric(s), sumRegisters(s)
+	if math.Float64bits(got) != math.Float64bits(want) {
+		t.Errorf("sumRegisters(%v) = %v, want %v", s, got, want)
+	}
+}
+
+func TestMaxRegisters(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 7, 8, 9, 64, 1000} {
+		checkMaxRegisters(t, randRegisters(r, n, 65), randRegisters(r, n, 65))
+		checkMaxRegisters(t, randRegisters(r, n, 255), randRegisters(r, n, 255))
+	}
+
+	a := []uint8{0, 0x7f, 0x80, 0xff, 0x80, 0x7f, 1, 0xfe}
+	b := []uint8{0xff, 0x80, 0x7f, 0, 0x80, 0x7f, 0xfe, 1}
+	checkMaxRegisters(t, a, b)
+	checkMaxRegisters(t, b, a)
+}
+
+func TestSumRegisters(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 16, 1 << 14} {
+		checkSumRegisters(t, randRegisters(r, n, 65))
+	}
+	checkSumRegisters(t, []uint8{33, 0, 63, 12, 62, 5, 53, 64, 200, 255})
+}
+
+func FuzzMaxRegisters(f *testing.F) {
+	f.Add([]byte{0, 0x7f, 0x80, 0xff, 0x80, 0x7f, 1, 0xfe, 3})
+	f.Add([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n := len(b) / 2
+		checkMaxRegisters(t, b[:n], b[n:2*n])
+	})
+}
+
+func FuzzSumRegisters(f *testing.F) {
+	f.Add([]byte{33, 0, 63, 12, 62, 5, 53})
+	f.Fuzz(func(t *testing.T, s []byte) {
+		checkSumRegisters(t, s)
+	})
+}
+
+func BenchmarkMaxRegisters(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	dst, src := randRegisters(r, 1<<14, 30), randRegisters(r, 1<<14, 30)
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		maxRegisters(dst, src)
+	}
+}
+
+func BenchmarkMaxRegistersGeneric(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	dst, src := randRegisters(r, 1<<14, 30), randRegisters(r, 1<<14, 30)
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		maxRegistersGeneric(dst, src)
+	}
+}
+
+func BenchmarkSumRegisters(b *testing.B) {
+	s := randRegisters(rand.New(rand.NewSource(1)), 1<<14, 30)
+	b.SetBytes(int64(len(s)))
+	for i := 0; i < b.N; i++ {
+		sumRegisters(s)
+	}
+}
+
+func BenchmarkSumRegistersGeneric(b *testing.B) {
+	s := randRegisters(rand.New(rand.NewSource(1)), 1<<14, 30)
+	b.SetBytes(int64(len(s)))
+	for i := 0; i < b.N; i++ {
+		sumRegistersGeneric(s)
+	}
+}
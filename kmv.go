@@ -0,0 +1,257 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+)
+
+// KMV is a K-Minimum-Values, or bottom-k, sketch. It keeps the k smallest
+// distinct hashes it has seen, which are a uniform sample of the distinct
+// items. While it has seen fewer than k distinct hashes its count is exact.
+//
+// Beyond that, the relative standard error of Count is about 1/sqrt(k-2).
+// Unlike HyperLogLog, intersections and Jaccard indices are estimated directly
+// from the sample rather than by inclusion-exclusion, so their error depends
+// on the size of the intersection rather than on the size of the sets. Each
+// hash takes eight bytes, so a KMV takes about 8k bytes.
+//
+// Described in Kevin Beyer et al., "On Synopses for Distinct-Value Estimation
+// Under Multiset Operations" (2007):
+// https://dl.acm.org/doi/10.1145/1247480.1247504
+type KMV struct {
+	k      int
+	hashes []uint64 // ascending
+}
+
+// NewKMV returns a new initialized KMV keeping the k smallest hashes.
+func NewKMV(k int) (*KMV, error) {
+	if k < 2 || k > math.MaxInt32 {
+		return nil, errors.New("k must be between 2 and 2^31-1")
+	}
+	return &KMV{k: k}, nil
+}
+
+// Clear sets KMV h back to its initial state.
+func (h *KMV) Clear() {
+	h.hashes = nil
+}
+
+// Clone returns a copy of KMV h that shares no state with it.
+func (h *KMV) Clone() *KMV {
+	c := *h
+	c.hashes = append([]uint64(nil), h.hashes...)
+	return &c
+}
+
+// Add adds a new item to KMV h.
+func (h *KMV) Add(item Hash64) {
+	h.addHash(item.Sum64())
+}
+
+func (h *KMV) addHash(x uint64) {
+	n := len(h.hashes)
+	if n == h.k && x >= h.hashes[n-1] {
+		return
+	}
+
+	i := sort.Search(n, func(i int) bool { return h.hashes[i] >= x })
+	if i < n && h.hashes[i] == x {
+		return
+	}
+	if n < h.k {
+		h.hashes = append(h.hashes, 0)
+	}
+	copy(h.hashes[i+1:], h.hashes[i:])
+	h.hashes[i] = x
+}
+
+// AddHashes adds a batch of already hashed items to KMV h.
+func (h *KMV) AddHashes(xs []uint64) {
+	for _, x := range xs {
+		h.addHash(x)
+	}
+}
+
+// Hashes returns the hashes kept by KMV h in ascending order. They are a
+// uniform sample of the distinct hashes added.
+func (h *KMV) Hashes() []uint64 {
+	return append([]uint64(nil), h.hashes...)
+}
+
+// Returns the k smallest distinct hashes of ascending lists a and b.
+func mergeHashes(a, b []uint64, k int) []uint64 {
+	n := len(a) + len(b)
+	if n > k {
+		n = k
+	}
+	out := make([]uint64, 0, n)
+	for len(out) < k && (len(a) > 0 || len(b) > 0) {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0] < b[0]):
+			out, a = append(out, a[0]), a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			out, b = append(out, b[0]), b[1:]
+		default:
+			out, a, b = append(out, a[0]), a[1:], b[1:]
+		}
+	}
+	return out
+}
+
+// Estimates the number of distinct hashes from the k smallest, or counts them
+// exactly if there are fewer than k.
+func estimateKMV(hashes []uint64, k int) float64 {
+	if len(hashes) < k {
+		return float64(len(hashes))
+	}
+	// The kth smallest hash as a fraction of the hash space.
+	u := (float64(hashes[k-1]) + 1) / (1 << 64)
+	return float64(k-1) / u
+}
+
+// Count returns the cardinality estimate.
+func (h *KMV) Count() uint64 {
+	return uint64(estimateKMV(h.hashes, h.k))
+}
+
+// Merge takes another KMV and combines it with KMV h.
+func (h *KMV) Merge(other *KMV) error {
+	if h.k != other.k {
+		return errors.New("k must be equal")
+	}
+	h.hashes = mergeHashes(h.hashes, other.hashes, h.k)
+	return nil
+}
+
+// Returns the number of hashes in the union sample of KMV h and other that are
+// kept by both, and the union sample itself. A hash among the k smallest of
+// the union is among the k smallest of any sketch that saw it, so it is kept
+// by both exactly when both saw it.
+func (h *KMV) overlap(other *KMV) (int, []uint64, error) {
+	if h.k != other.k {
+		return 0, nil, errors.New("k must be equal")
+	}
+
+	union := mergeHashes(h.hashes, other.hashes, h.k)
+	var both int
+	a, b := h.hashes, other.hashes
+	for _, x := range union {
+		for len(a) > 0 && a[0] < x {
+			a = a[1:]
+		}
+		for len(b) > 0 && b[0] < x {
+			b = b[1:]
+		}
+		if len(a) > 0 && len(b) > 0 && a[0] == x && b[0] == x {
+			both++
+		}
+	}
+	return both, union, nil
+}
+
+// Jaccard returns the estimated Jaccard index of KMV h and other: the fraction
+// of the union sample that both sketches saw. It is exact if their union has
+// fewer than k distinct hashes, and 0 if both are empty.
+func (h *KMV) Jaccard(other *KMV) (float64, error) {
+	both, union, err := h.overlap(other)
+	if err != nil || len(union) == 0 {
+		return 0, err
+	}
+	return float64(both) / float64(len(union)), nil
+}
+
+// Intersect returns the estimated cardinality of the intersection of KMV h
+// and other, the product of their Jaccard index and the estimated size of
+// their union. It is exact if their union has fewer than k distinct hashes.
+func (h *KMV) Intersect(other *KMV) (uint64, error) {
+	both, union, err := h.overlap(other)
+	if err != nil || len(union) == 0 {
+		return 0, err
+	}
+	if len(union) < h.k {
+		return uint64(both), nil
+	}
+	j := float64(both) / float64(len(union))
+	return uint64(math.Round(j * estimateKMV(union, h.k))), nil
+}
+
+// WriteTo writes KMV h to w in the format of MarshalBinary.
+func (h *KMV) WriteTo(w io.Writer) (int64, error) {
+	b := make([]byte, 0, 2+2*binary.MaxVarintLen64+len(h.hashes)*binary.MaxVarintLen64)
+	b = append(b, encodingVersion, kindKMV)
+	b = binary.AppendUvarint(b, uint64(h.k))
+	b = binary.AppendUvarint(b, uint64(len(h.hashes)))
+	var last uint64
+	for _, x := range h.hashes {
+		b = binary.AppendUvarint(b, x-last)
+		last = x
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// ReadFrom reads a KMV written by WriteTo or MarshalBinary from r into h. It
+// reads exactly one sketch and never reads past its end, so several sketches
+// can be read one after another from the same reader. It returns io.EOF if r
+// is already at its end.
+func (h *KMV) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	kind, _, err := readHeader(cr)
+	if err != nil {
+		return cr.n, err
+	}
+	if kind != kindKMV {
+		return cr.n, errEncodingKind
+	}
+
+	k, err := readUvarint(cr)
+	if err != nil {
+		return cr.n, err
+	}
+	if k < 2 || k > math.MaxInt32 {
+		return cr.n, errors.New("k must be between 2 and 2^31-1")
+	}
+	n, err := readUvarint(cr)
+	if err != nil {
+		return cr.n, err
+	}
+	if n > k {
+		return cr.n, errors.New("too many hashes")
+	}
+
+	// The hashes are appended as they are read, so a corrupt count cannot
+	// cause a large allocation.
+	g := KMV{k: int(k)}
+	var last uint64
+	for i := uint64(0); i < n; i++ {
+		d, err := readUvarint(cr)
+		if err != nil {
+			return cr.n, err
+		}
+		if (i > 0 && d == 0) || last+d < last {
+			return cr.n, errors.New("hashes are not ascending")
+		}
+		last += d
+		g.hashes = append(g.hashes, last)
+	}
+	*h = g
+	return cr.n, nil
+}
+
+// MarshalBinary encodes KMV h in a stable binary format.
+func (h *KMV) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := h.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a KMV encoded with MarshalBinary into h.
+func (h *KMV) UnmarshalBinary(b []byte) error {
+	return unmarshalBinary(b, h.ReadFrom)
+}
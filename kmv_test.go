@@ -0,0 +1,209 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Returns a KMV keeping k hashes of the integers in [lo, hi).
+func kmvRange(k int, lo, hi uint64) *KMV {
+	h, _ := NewKMV(k)
+	for i := lo; i < hi; i++ {
+		h.Add(fakeHash64(HashUint64(i)))
+	}
+	return h
+}
+
+func TestKMVNew(t *testing.T) {
+	for _, k := range []int{-1, 0, 1} {
+		if _, err := NewKMV(k); err == nil {
+			t.Error("expected error for k", k)
+		}
+	}
+}
+
+func TestKMVAdd(t *testing.T) {
+	h, _ := NewKMV(3)
+	h.AddHashes([]uint64{50, 10, 10})
+	if h.Count() != 2 {
+		t.Error(h.Count())
+	}
+	h.AddHashes([]uint64{40, 10, 30})
+	if got := h.Hashes(); !reflect.DeepEqual(got, []uint64{10, 30, 40}) {
+		t.Error(got)
+	}
+
+	h.Add(fakeHash64(20))
+	h.Add(fakeHash64(40))
+	h.Add(fakeHash64(^uint64(0)))
+	if got := h.Hashes(); !reflect.DeepEqual(got, []uint64{10, 20, 30}) {
+		t.Error(got)
+	}
+
+	h.Hashes()[0] = 0
+	if h.hashes[0] != 10 {
+		t.Error("Hashes shares state with the sketch")
+	}
+}
+
+func TestKMVCount(t *testing.T) {
+	for _, n := range []uint64{0, 1, 100, 1023, 1024, 10000, 1000000} {
+		h := kmvRange(1024, 0, n)
+		got := float64(h.Count())
+		if n < 1024 && got != float64(n) {
+			t.Errorf("n = %d: got %v, want exact", n, got)
+		}
+		if math.Abs(got-float64(n)) > 0.1*float64(n) {
+			t.Errorf("n = %d: got %v", n, got)
+		}
+	}
+}
+
+func TestKMVMerge(t *testing.T) {
+	a := kmvRange(256, 0, 3000)
+	b := kmvRange(256, 2000, 5000)
+	want := kmvRange(256, 0, 5000)
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.hashes, want.hashes) {
+		t.Error("merged hashes differ")
+	}
+
+	small := kmvRange(256, 0, 10)
+	c := small.Clone()
+	c.Merge(kmvRange(256, 5, 20))
+	if c.Count() != 20 || small.Count() != 10 {
+		t.Error(c.Count(), small.Count())
+	}
+
+	other, _ := NewKMV(128)
+	if err := a.Merge(other); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := a.Jaccard(other); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := a.Intersect(other); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestKMVMergeLargeK(t *testing.T) {
+	// The merged sample is only as large as the inputs, however large k is.
+	a, _ := NewKMV(1 << 28)
+	b, _ := NewKMV(1 << 28)
+	a.AddHashes([]uint64{1, 2, 3})
+	b.AddHashes([]uint64{2, 4})
+	if n := testing.AllocsPerRun(10, func() { mergeHashes(a.hashes, b.hashes, a.k) }); n > 1 {
+		t.Error(n)
+	}
+	if out := mergeHashes(a.hashes, b.hashes, a.k); cap(out) != 5 {
+		t.Error(cap(out))
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Hashes(); !reflect.DeepEqual(got, []uint64{1, 2, 3, 4}) {
+		t.Error(got)
+	}
+	if n, _ := a.Intersect(b); n != 2 {
+		t.Error(n)
+	}
+}
+
+func TestKMVSetOperations(t *testing.T) {
+	// Exact while the union has fewer than k hashes.
+	a := kmvRange(1024, 0, 300)
+	b := kmvRange(1024, 200, 600)
+	if n, _ := a.Intersect(b); n != 100 {
+		t.Error(n)
+	}
+	if j, _ := a.Jaccard(b); j != 100.0/600 {
+		t.Error(j)
+	}
+
+	a = kmvRange(4096, 0, 100000)
+	b = kmvRange(4096, 50000, 150000)
+	if j, _ := a.Jaccard(b); math.Abs(j-1.0/3) > 0.03 {
+		t.Error(j)
+	}
+	if n, _ := a.Intersect(b); math.Abs(float64(n)-50000) > 5000 {
+		t.Error(n)
+	}
+
+	b = kmvRange(4096, 100000, 200000)
+	if n, _ := a.Intersect(b); n != 0 {
+		t.Error(n)
+	}
+
+	empty, _ := NewKMV(4096)
+	if j, err := empty.Jaccard(empty); j != 0 || err != nil {
+		t.Error(j, err)
+	}
+	if n, err := a.Intersect(empty); n != 0 || err != nil {
+		t.Error(n, err)
+	}
+}
+
+func TestKMVBinary(t *testing.T) {
+	h, _ := NewKMV(300)
+	h.AddHashes([]uint64{0, 1, 300, ^uint64(0)})
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{1, kindKMV, 0xac, 0x02, 4, 0, 1, 0xab, 0x02,
+		0xd3, 0xfd, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	if !bytes.Equal(b, want) {
+		t.Errorf("got %x, want %x", b, want)
+	}
+
+	var g KMV
+	if err := g.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if g.k != 300 || !reflect.DeepEqual(g.hashes, h.hashes) {
+		t.Error(g)
+	}
+
+	var buf bytes.Buffer
+	empty, _ := NewKMV(2)
+	empty.WriteTo(&buf)
+	h.WriteTo(&buf)
+	for _, want := range []*KMV{empty, h} {
+		if _, err := g.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if g.k != want.k || len(g.hashes) != len(want.hashes) {
+			t.Error(g)
+		}
+	}
+	if _, err := g.ReadFrom(&buf); err != io.EOF {
+		t.Error(err)
+	}
+
+	for _, bad := range [][]byte{
+		{1, kindKMV},
+		{1, kindKMV, 1, 0},
+		{1, kindKMV, 2, 3, 0, 1, 1},
+		{1, kindKMV, 2, 2, 5, 0},
+		{1, kindKMV, 2, 2, 1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		{1, kindKMV, 0x82, 0x00, 0},
+		{1, kindKMV, 2, 0, 0},
+		{1, kindHLL, 4},
+	} {
+		if err := g.UnmarshalBinary(bad); err == nil {
+			t.Errorf("%x: expected error", bad)
+		}
+	}
+
+	plus, _ := NewPlus(4)
+	if err := plus.UnmarshalBinary(b); err != errEncodingKind {
+		t.Error(err)
+	}
+}
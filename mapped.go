@@ -0,0 +1,155 @@
+package hyperloglog
+
+import (
+	"errors"
+	"os"
+)
+
+// A mapped file starts with mappedMagic, a version byte, the precision and two
+// reserved zero bytes, followed by the m registers.
+const mappedMagic = "HLLM"
+const mappedVersion = 1
+const mappedHeaderLen = 8
+
+var errMappedClosed = errors.New("mapped sketch is closed")
+
+// MappedPlus is a HyperLogLogPlus in the normal representation whose registers
+// live in a memory-mapped file rather than on the Go heap. Add, Merge and Count
+// work directly on the mapped registers, so reopening the file restores the
+// sketch without decoding it.
+//
+// On platforms without mmap support the file is read into memory when it is
+// opened and written back by Sync and Close.
+//
+// A MappedPlus is not safe for concurrent use, and must not be used after
+// Close, except that Close may be called again.
+type MappedPlus struct {
+	h    HyperLogLogPlus
+	f    *os.File
+	data []byte
+}
+
+// OpenMappedPlus opens the mapped sketch in the file at path, creating it with
+// the given precision if it does not exist. An existing file must have been
+// created with the same precision.
+func OpenMappedPlus(path string, precision uint8) (*MappedPlus, error) {
+	if precision > pPrime-1 || precision < 4 {
+		return nil, errors.New("precision must be between 4 and 24")
+	}
+	m := uint32(1) << precision
+	size := int64(mappedHeaderLen) + int64(m)
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	created := fi.Size() == 0
+	if created {
+		if err := f.Truncate(size); err != nil {
+			f.Close()
+			return nil, err
+		}
+	} else if fi.Size() != size {
+		f.Close()
+		return nil, errors.New("mapped file size does not match precision")
+	}
+
+	data, err := mapFile(f, int(size))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if created {
+		copy(data, mappedMagic)
+		data[4] = mappedVersion
+		data[5] = precision
+	} else if err := checkMappedHeader(data, precision); err != nil {
+		unmapFile(data)
+		f.Close()
+		return nil, err
+	}
+
+	mp := &MappedPlus{f: f, data: data}
+	mp.h.p = precision
+	mp.h.m = m
+	mp.h.reg = data[mappedHeaderLen:]
+	return mp, nil
+}
+
+func checkMappedHeader(data []byte, precision uint8) error {
+	if string(data[:4]) != mappedMagic {
+		return errors.New("not a mapped sketch file")
+	}
+	if data[4] != mappedVersion {
+		return errors.New("unsupported mapped sketch version")
+	}
+	if data[5] != precision {
+		return errors.New("precisions must be equal")
+	}
+	return nil
+}
+
+// Add adds a new item to MappedPlus mp.
+func (mp *MappedPlus) Add(item Hash64) {
+	mp.h.Add(item)
+}
+
+// AddHashes adds a batch of already hashed items to MappedPlus mp.
+func (mp *MappedPlus) AddHashes(xs []uint64) {
+	mp.h.AddHashes(xs)
+}
+
+// Merge takes another HyperLogLogPlus and combines it with MappedPlus mp.
+func (mp *MappedPlus) Merge(other *HyperLogLogPlus) error {
+	return mp.h.Merge(other)
+}
+
+// Count returns the cardinality estimate.
+func (mp *MappedPlus) Count() uint64 {
+	return mp.h.Count()
+}
+
+// Clear zeroes the registers of MappedPlus mp.
+func (mp *MappedPlus) Clear() {
+	for i := range mp.h.reg {
+		mp.h.reg[i] = 0
+	}
+}
+
+// Snapshot returns a copy of MappedPlus mp on the Go heap.
+func (mp *MappedPlus) Snapshot() *HyperLogLogPlus {
+	return mp.h.Clone()
+}
+
+// Sync flushes changes to the registers of MappedPlus mp to its file.
+func (mp *MappedPlus) Sync() error {
+	if mp.data == nil {
+		return errMappedClosed
+	}
+	return syncFile(mp.f, mp.data)
+}
+
+// Close syncs and unmaps MappedPlus mp and closes its file. Closing it again
+// does nothing.
+func (mp *MappedPlus) Close() error {
+	if mp.data == nil {
+		return nil
+	}
+	err := mp.Sync()
+	if err2 := unmapFile(mp.data); err == nil {
+		err = err2
+	}
+	if err2 := mp.f.Close(); err == nil {
+		err = err2
+	}
+	mp.data = nil
+	mp.h.reg = nil
+	return err
+}
@@ -0,0 +1,114 @@
+package hyperloglog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMappedPlus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sketch")
+	mp, err := OpenMappedPlus(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, _ := NewPlus(10)
+	for i := 0; i < 5000; i++ {
+		x := fakeHash64(uint64(i) * 0x9e3779b97f4a7c15)
+		mp.Add(x)
+		h.Add(x)
+	}
+	if mp.Count() != h.Count() {
+		t.Error(mp.Count(), h.Count())
+	}
+
+	other, _ := NewPlus(10)
+	other.Add(fakeHash64(0xffffffffffffffff))
+	other.Add(fakeHash64(0x0000000000000001))
+	if err := mp.Merge(other); err != nil {
+		t.Error(err)
+	}
+	h.Merge(other)
+	if !mp.Snapshot().Equal(h) {
+		t.Error("mapped sketch differs")
+	}
+	if err := mp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mp, err = OpenMappedPlus(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp.Close()
+	if !mp.Snapshot().Equal(h) {
+		t.Error("reopened sketch differs")
+	}
+
+	s := mp.Snapshot()
+	mp.Clear()
+	if mp.Count() != 0 {
+		t.Error(mp.Count())
+	}
+	if !s.Equal(h) {
+		t.Error("Clear changed snapshot")
+	}
+	if err := mp.Sync(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMappedPlusAddHashes(t *testing.T) {
+	mp, err := OpenMappedPlus(filepath.Join(t.TempDir(), "sketch"), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mp.Close()
+
+	hashes := make([]uint64, 1000)
+	for i := range hashes {
+		hashes[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+	mp.AddHashes(hashes)
+	h, _ := NewPlus(8)
+	h.AddHashes(hashes)
+	if !mp.Snapshot().Equal(h) {
+		t.Error("mapped sketch differs")
+	}
+}
+
+func TestMappedPlusError(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := OpenMappedPlus(filepath.Join(dir, "a"), 3); err == nil {
+		t.Error("expected precision error")
+	}
+
+	mp, err := OpenMappedPlus(filepath.Join(dir, "a"), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp.Close()
+	if err := mp.Close(); err != nil {
+		t.Error("second Close:", err)
+	}
+	if err := mp.Sync(); err != errMappedClosed {
+		t.Error(err)
+	}
+	if _, err := OpenMappedPlus(filepath.Join(dir, "a"), 9); err == nil {
+		t.Error("expected size error")
+	}
+
+	b, _ := os.ReadFile(filepath.Join(dir, "a"))
+	b[5] = 9
+	os.WriteFile(filepath.Join(dir, "b"), b[:8+128], 0644)
+	if _, err := OpenMappedPlus(filepath.Join(dir, "b"), 7); err == nil {
+		t.Error("expected precision error")
+	}
+	b[5] = 8
+	b[0] = 'X'
+	os.WriteFile(filepath.Join(dir, "c"), b, 0644)
+	if _, err := OpenMappedPlus(filepath.Join(dir, "c"), 8); err == nil {
+		t.Error("expected header error")
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !(darwin || dragonfly || freebsd || linux || openbsd)
+
+package hyperloglog
+
+import (
+	"io"
+	"os"
+)
+
+// Without mmap the file is read into memory and written back by syncFile.
+
+func mapFile(f *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(f, 0, int64(size)), data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
+
+func syncFile(f *os.File, data []byte) error {
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,225 @@
+// Package promtext publishes the estimates of HyperLogLog and HyperLogLog++
+// sketches as gauges in the Prometheus text exposition format, without
+// depending on the Prometheus client library.
+//
+// Register sketches, or Windows of them, with a Registry and serve it as a
+// scrape target:
+//
+//	reg := promtext.NewRegistry()
+//	w := promtext.NewWindow(14, time.Minute)
+//	reg.Register("unique_clients", "Distinct clients in the last minute.",
+//		map[string]string{"service": "api"}, w)
+//	http.Handle("/metrics", reg)
+package promtext
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Counter is implemented by the sketch types of the hyperloglog package and by
+// Window.
+type Counter interface {
+	Count() uint64
+}
+
+// CounterFunc adapts a function to a Counter, for example to take a lock
+// around a sketch that is written to concurrently.
+type CounterFunc func() uint64
+
+// Count calls f.
+func (f CounterFunc) Count() uint64 {
+	return f()
+}
+
+type series struct {
+	labels string
+	c      Counter
+}
+
+type metric struct {
+	help   string
+	series map[string]series
+}
+
+// Registry holds Counters to publish as gauges. It is safe for concurrent
+// use, but the Counters it holds must be safe to count while other goroutines
+// use them.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]*metric
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+func validName(name string, colons bool) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c == ':' && colons:
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Formats labels as they appear in the exposition format, sorted by name.
+func formatLabels(labels map[string]string) (string, error) {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if !validName(name, false) || strings.HasPrefix(name, "__") {
+			return "", fmt.Errorf("invalid label name %q", name)
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// Register publishes the estimate of c as a gauge with the given name, help
+// text and labels. Each metric name can have many series with different
+// labels, but they must all share the same help text.
+func (r *Registry) Register(name, help string, labels map[string]string, c Counter) error {
+	if !validName(name, true) {
+		return fmt.Errorf("invalid metric name %q", name)
+	}
+	l, err := formatLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.metrics[name]
+	if !ok {
+		m = &metric{help: help, series: make(map[string]series)}
+		r.metrics[name] = m
+	}
+	if m.help != help {
+		return fmt.Errorf("metric %s already registered with different help", name)
+	}
+	if _, ok := m.series[l]; ok {
+		return errors.New("series already registered: " + name + l)
+	}
+	m.series[l] = series{l, c}
+	return nil
+}
+
+// Unregister removes the series with the given name and labels, reporting
+// whether it was registered.
+func (r *Registry) Unregister(name string, labels map[string]string) bool {
+	l, err := formatLabels(labels)
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.metrics[name]
+	if !ok {
+		return false
+	}
+	if _, ok := m.series[l]; !ok {
+		return false
+	}
+	delete(m.series, l)
+	if len(m.series) == 0 {
+		delete(r.metrics, name)
+	}
+	return true
+}
+
+// WriteTo writes the current estimate of every registered series to w in the
+// text exposition format, with metrics and series in sorted order.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	type snapshot struct {
+		name, help string
+		series     []series
+	}
+	metrics := make([]snapshot, len(names))
+	for i, name := range names {
+		m := r.metrics[name]
+		s := snapshot{name: name, help: m.help}
+		for _, ser := range m.series {
+			s.series = append(s.series, ser)
+		}
+		sort.Slice(s.series, func(i, j int) bool { return s.series[i].labels < s.series[j].labels })
+		metrics[i] = s
+	}
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		if m.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", m.name, helpEscaper.Replace(m.help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", m.name)
+		for _, s := range m.series {
+			bw.WriteString(m.name)
+			bw.WriteString(s.labels)
+			bw.WriteByte(' ')
+			bw.WriteString(strconv.FormatUint(s.c.Count(), 10))
+			bw.WriteByte('\n')
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+// ServeHTTP serves the registry as a Prometheus scrape target.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
@@ -0,0 +1,109 @@
+package promtext
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+type fakeHash64 uint64
+
+func (f fakeHash64) Sum64() uint64 { return uint64(f) }
+
+type fakeHash32 uint32
+
+func (f fakeHash32) Sum32() uint32 { return uint32(f) }
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	hpp, _ := hyperloglog.NewPlus(14)
+	for i := 0; i < 3; i++ {
+		hpp.Add(fakeHash64(uint64(i+1) * 0x9e3779b97f4a7c15))
+	}
+	hll, _ := hyperloglog.New(14)
+	hll.Add(fakeHash32(0x12345678))
+
+	var mu sync.Mutex
+	locked := CounterFunc(func() uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return hpp.Count()
+	})
+
+	for _, err := range []error{
+		r.Register("unique_clients", "Distinct clients.\nBy service.", map[string]string{"service": "api"}, hpp),
+		r.Register("unique_clients", "Distinct clients.\nBy service.", map[string]string{"service": `we"b\`, "zone": "a\nb"}, hll),
+		r.Register("unique_clients", "Distinct clients.\nBy service.", nil, locked),
+		r.Register("a:b", "", nil, CounterFunc(func() uint64 { return 7 })),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := `# TYPE a:b gauge
+a:b 7
+# HELP unique_clients Distinct clients.\nBy service.
+# TYPE unique_clients gauge
+unique_clients 3
+unique_clients{service="api"} 3
+unique_clients{service="we\"b\\",zone="a\nb"} 1
+`
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil {
+		t.Error(err)
+	}
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+	if n != int64(len(want)) {
+		t.Error(n)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" || w.Body.String() != want {
+		t.Error(w.Header(), w.Body.String())
+	}
+
+	if !r.Unregister("a:b", nil) || r.Unregister("a:b", nil) {
+		t.Error("Unregister")
+	}
+	if r.Unregister("unique_clients", map[string]string{"service": "web"}) {
+		t.Error("Unregister removed missing series")
+	}
+	b.Reset()
+	r.WriteTo(&b)
+	if strings.Contains(b.String(), "a:b") {
+		t.Error(b.String())
+	}
+}
+
+func TestRegistryError(t *testing.T) {
+	r := NewRegistry()
+	c := CounterFunc(func() uint64 { return 0 })
+	r.Register("m", "help", map[string]string{"a": "1"}, c)
+
+	for _, tc := range []struct {
+		name, help string
+		labels     map[string]string
+	}{
+		{"", "help", nil},
+		{"1m", "help", nil},
+		{"m-1", "help", nil},
+		{"m", "help", map[string]string{"a:b": "1"}},
+		{"m", "help", map[string]string{"__a": "1"}},
+		{"m", "help", map[string]string{"1a": "1"}},
+		{"m", "other help", nil},
+		{"m", "help", map[string]string{"a": "1"}},
+	} {
+		if err := r.Register(tc.name, tc.help, tc.labels, c); err == nil {
+			t.Error("accepted", tc)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package promtext
+
+import (
+	"sync"
+	"time"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+// Window counts distinct items in consecutive periods of fixed length, such as
+// unique clients per minute. Periods are aligned to multiples of the period
+// length since the zero time, and the sketch for a period is replaced with an
+// empty one when the next period starts.
+//
+// Count returns the estimate for the last complete period, so a gauge shows a
+// stable value for a whole period rather than one that climbs from zero. A
+// Window is safe for concurrent use.
+type Window struct {
+	mu        sync.Mutex
+	precision uint8
+	period    time.Duration
+	now       func() time.Time
+
+	start    time.Time
+	current  *hyperloglog.HyperLogLogPlus
+	previous *hyperloglog.HyperLogLogPlus
+}
+
+// NewWindow returns a Window with periods of the given length, counting with
+// HyperLogLog++ sketches of the given precision. It panics if precision is not
+// between 4 and 24 or period is not positive.
+func NewWindow(precision uint8, period time.Duration) *Window {
+	if period <= 0 {
+		panic("promtext: non-positive window period")
+	}
+	if _, err := hyperloglog.NewPlus(precision); err != nil {
+		panic("promtext: " + err.Error())
+	}
+	return &Window{precision: precision, period: period, now: time.Now}
+}
+
+// Rotates the sketches if the current period has ended. Sketches are only
+// created when needed, so an idle Window holds no sketches.
+func (w *Window) rotate() {
+	start := w.now().Truncate(w.period)
+	if start.Equal(w.start) {
+		return
+	}
+
+	if start.Sub(w.start) == w.period {
+		w.previous = w.current
+	} else {
+		w.previous = nil
+	}
+	w.current = nil
+	w.start = start
+}
+
+// Add adds item to the current period.
+func (w *Window) Add(item hyperloglog.Hash64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rotate()
+	if w.current == nil {
+		w.current, _ = hyperloglog.NewPlus(w.precision)
+	}
+	w.current.Add(item)
+}
+
+// AddHashes adds a batch of already hashed items to the current period.
+func (w *Window) AddHashes(xs []uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rotate()
+	if w.current == nil {
+		w.current, _ = hyperloglog.NewPlus(w.precision)
+	}
+	w.current.AddHashes(xs)
+}
+
+// Count returns the estimate for the last complete period, or 0 if nothing was
+// added in it.
+func (w *Window) Count() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rotate()
+	if w.previous == nil {
+		return 0
+	}
+	return w.previous.Count()
+}
+
+// CountCurrent returns the estimate for the period in progress.
+func (w *Window) CountCurrent() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rotate()
+	if w.current == nil {
+		return 0
+	}
+	return w.current.Count()
+}
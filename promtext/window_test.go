@@ -0,0 +1,85 @@
+package promtext
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestWindow(t *testing.T) {
+	clock := &fakeClock{time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)}
+	w := NewWindow(14, time.Minute)
+	w.now = clock.now
+
+	if w.Count() != 0 || w.CountCurrent() != 0 {
+		t.Error("empty window should count 0")
+	}
+
+	for i := 0; i < 5; i++ {
+		w.Add(fakeHash64(uint64(i+1) * 0x9e3779b97f4a7c15))
+	}
+	if w.Count() != 0 || w.CountCurrent() != 5 {
+		t.Error(w.Count(), w.CountCurrent())
+	}
+
+	// The next period starts at 12:01 rather than 60s after the first add.
+	clock.t = clock.t.Add(40 * time.Second)
+	w.AddHashes([]uint64{1 << 60, 2 << 60})
+	if w.Count() != 5 || w.CountCurrent() != 2 {
+		t.Error(w.Count(), w.CountCurrent())
+	}
+
+	clock.t = clock.t.Add(time.Minute)
+	if w.Count() != 2 || w.CountCurrent() != 0 {
+		t.Error(w.Count(), w.CountCurrent())
+	}
+
+	// A period with no adds counts 0.
+	clock.t = clock.t.Add(time.Minute)
+	if w.Count() != 0 {
+		t.Error(w.Count())
+	}
+
+	// Skipping periods forgets the last active one.
+	w.Add(fakeHash64(1 << 62))
+	clock.t = clock.t.Add(3 * time.Minute)
+	if w.Count() != 0 {
+		t.Error(w.Count())
+	}
+}
+
+func TestWindowRegistry(t *testing.T) {
+	clock := &fakeClock{time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	w := NewWindow(10, time.Minute)
+	w.now = clock.now
+	w.Add(fakeHash64(1 << 60))
+	clock.t = clock.t.Add(time.Minute)
+
+	r := NewRegistry()
+	r.Register("unique_clients_per_minute", "", nil, w)
+	var b strings.Builder
+	r.WriteTo(&b)
+	if b.String() != "# TYPE unique_clients_per_minute gauge\nunique_clients_per_minute 1\n" {
+		t.Error(b.String())
+	}
+}
+
+func TestNewWindowPanics(t *testing.T) {
+	for _, f := range []func(){
+		func() { NewWindow(3, time.Minute) },
+		func() { NewWindow(14, 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
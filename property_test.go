@@ -0,0 +1,212 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// A reference implementation of the registers of HyperLogLog and
+// HyperLogLog++, written as plainly as possible.
+type refSketch struct {
+	bits uint8
+	p    uint8
+	reg  []uint8
+}
+
+func newRefSketch(bits, p uint8) *refSketch {
+	return &refSketch{bits, p, make([]uint8, 1<<p)}
+}
+
+func (s *refSketch) add(x uint64) {
+	i := x >> (s.bits - s.p)
+	r := uint8(1)
+	for bit := int(s.bits-s.p) - 1; bit >= 0 && x&(1<<uint(bit)) == 0; bit-- {
+		r++
+	}
+	if r > s.reg[i] {
+		s.reg[i] = r
+	}
+}
+
+// A random stream of hashes for a sketch of precision p. Some hashes have
+// runs of zeros after the index so that ranks are spread out, and the sparse
+// representation has to record them.
+type hashStream struct {
+	p  uint8
+	xs []uint64
+}
+
+func (hashStream) Generate(r *rand.Rand, size int) reflect.Value {
+	s := hashStream{p: uint8(4 + r.Intn(11))}
+	n := r.Intn(3 << s.p)
+	for i := 0; i < n; i++ {
+		x := r.Uint64()
+		if r.Intn(4) == 0 {
+			x &^= (1<<uint(r.Intn(64-int(s.p))) - 1) << uint(r.Intn(int(s.p)))
+		}
+		s.xs = append(s.xs, x)
+	}
+	return reflect.ValueOf(s)
+}
+
+var quickConfig = &quick.Config{MaxCount: 100, Rand: rand.New(rand.NewSource(1))}
+
+func buildPlus(p uint8, xs []uint64, normal bool) *HyperLogLogPlus {
+	h, _ := NewPlus(p)
+	if normal {
+		h.toNormal()
+	}
+	for _, x := range xs {
+		h.Add(fakeHash64(x))
+	}
+	return h
+}
+
+func TestPropertyPlusMatchesReference(t *testing.T) {
+	f := func(s hashStream) bool {
+		ref := newRefSketch(64, s.p)
+		for _, x := range s.xs {
+			ref.add(x)
+		}
+
+		sparse := buildPlus(s.p, s.xs, false)
+		dense := buildPlus(s.p, s.xs, true)
+		sparse.mergeSparseAndToNormal()
+		return bytes.Equal(sparse.reg, ref.reg) && bytes.Equal(dense.reg, ref.reg) &&
+			sparse.Count() == dense.Count()
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyPlusMerge(t *testing.T) {
+	f := func(a, b hashStream, normalA, normalB bool) bool {
+		b.p = a.p
+		ref := newRefSketch(64, a.p)
+		for _, x := range append(a.xs, b.xs...) {
+			ref.add(x)
+		}
+
+		ab := buildPlus(a.p, a.xs, normalA)
+		ab.Merge(buildPlus(b.p, b.xs, normalB))
+		ba := buildPlus(b.p, b.xs, normalB)
+		ba.Merge(buildPlus(a.p, a.xs, normalA))
+		if ab.Count() != ba.Count() || ab.sparse != ba.sparse {
+			return false
+		}
+
+		// Both sketches are now in the same representation.
+		if ab.sparse && !reflect.DeepEqual(ab.sparseList, ba.sparseList) {
+			return false
+		}
+		ab.mergeSparseAndToNormal()
+		ba.mergeSparseAndToNormal()
+		return bytes.Equal(ab.reg, ref.reg) && bytes.Equal(ba.reg, ref.reg)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyPlusMergeAll(t *testing.T) {
+	f := func(a, b, c hashStream, normal uint8) bool {
+		b.p, c.p = a.p, a.p
+		ref := newRefSketch(64, a.p)
+		for _, x := range append(append(a.xs, b.xs...), c.xs...) {
+			ref.add(x)
+		}
+
+		h := buildPlus(a.p, a.xs, normal&1 != 0)
+		h.MergeAll(buildPlus(b.p, b.xs, normal&2 != 0), buildPlus(c.p, c.xs, normal&4 != 0))
+		h.mergeSparseAndToNormal()
+		return bytes.Equal(h.reg, ref.reg)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyPlusClear(t *testing.T) {
+	f := func(a, b hashStream, normal bool) bool {
+		b.p = a.p
+		h := buildPlus(a.p, a.xs, normal)
+		h.Clear()
+		if h.Count() != 0 {
+			return false
+		}
+		for _, x := range b.xs {
+			h.Add(fakeHash64(x))
+		}
+
+		fresh := buildPlus(b.p, b.xs, false)
+		if h.Count() != fresh.Count() {
+			return false
+		}
+		h.mergeSparseAndToNormal()
+		fresh.mergeSparseAndToNormal()
+		return bytes.Equal(h.reg, fresh.reg)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyHLLMatchesReference(t *testing.T) {
+	f := func(s hashStream, wide bool) bool {
+		bits := uint8(32)
+		h, _ := New(s.p)
+		if wide {
+			bits = 64
+			h, _ = New64(s.p)
+		}
+		ref := newRefSketch(bits, s.p)
+		for _, x := range s.xs {
+			if wide {
+				h.Add64(fakeHash64(x))
+				ref.add(x)
+			} else {
+				h.Add(fakeHash32(x >> 32))
+				ref.add(x >> 32)
+			}
+		}
+		return bytes.Equal(h.reg, ref.reg)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyHLLMerge(t *testing.T) {
+	build := func(p uint8, xs []uint64) *HyperLogLog {
+		h, _ := New64(p)
+		for _, x := range xs {
+			h.Add64(fakeHash64(x))
+		}
+		return h
+	}
+
+	f := func(a, b hashStream) bool {
+		b.p = a.p
+		ref := newRefSketch(64, a.p)
+		for _, x := range append(a.xs, b.xs...) {
+			ref.add(x)
+		}
+
+		ab, ba := build(a.p, a.xs), build(b.p, b.xs)
+		ab.Merge(build(b.p, b.xs))
+		ba.Merge(build(a.p, a.xs))
+		if !bytes.Equal(ab.reg, ref.reg) || !bytes.Equal(ba.reg, ref.reg) {
+			return false
+		}
+
+		ab.Clear()
+		return ab.Count() == 0 && bytes.Equal(ab.reg, make([]uint8, ab.m))
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
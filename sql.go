@@ -0,0 +1,50 @@
+package hyperloglog
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// Scan implements sql.Scanner, decoding a column written by Value into
+// HyperLogLog h.
+func (h *HyperLogLog) Scan(src interface{}) error {
+	b, err := scanBytes(src)
+	if err != nil {
+		return err
+	}
+	return h.UnmarshalBinary(b)
+}
+
+// Value implements driver.Valuer, encoding HyperLogLog h with MarshalBinary.
+func (h *HyperLogLog) Value() (driver.Value, error) {
+	return h.MarshalBinary()
+}
+
+// Scan implements sql.Scanner, decoding a column written by Value into
+// HyperLogLogPlus h.
+func (h *HyperLogLogPlus) Scan(src interface{}) error {
+	b, err := scanBytes(src)
+	if err != nil {
+		return err
+	}
+	return h.UnmarshalBinary(b)
+}
+
+// Value implements driver.Valuer, encoding HyperLogLogPlus h with
+// MarshalBinary.
+func (h *HyperLogLogPlus) Value() (driver.Value, error) {
+	return h.MarshalBinary()
+}
+
+func scanBytes(src interface{}) ([]byte, error) {
+	switch v := src.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case nil:
+		return nil, errors.New("cannot scan NULL into a sketch")
+	}
+	return nil, fmt.Errorf("cannot scan %T into a sketch", src)
+}
@@ -0,0 +1,141 @@
+package hyperloglog
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+// fakeDriver is a database/sql driver storing blobs by key. It understands
+// two statements: "put" with a key and a value, and "get" with a key.
+type fakeDriver struct {
+	mu    sync.Mutex
+	blobs map[string]driver.Value
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+type fakeRows struct {
+	v    driver.Value
+	done bool
+}
+
+func init() {
+	sql.Register("hllfake", &fakeDriver{blobs: make(map[string]driver.Value)})
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if query != "put" && query != "get" {
+		return nil, errors.New("unknown query")
+	}
+	return fakeStmt{c.d, query}, nil
+}
+
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (s fakeStmt) Close() error { return nil }
+
+func (s fakeStmt) NumInput() int {
+	if s.query == "put" {
+		return 2
+	}
+	return 1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.blobs[args[0].(string)] = args[1]
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	v, ok := s.d.blobs[args[0].(string)]
+	return &fakeRows{v: v, done: !ok}, nil
+}
+
+func (r *fakeRows) Columns() []string { return []string{"sketch"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0] = r.v
+	r.done = true
+	return nil
+}
+
+func TestSQL(t *testing.T) {
+	db, err := sql.Open("hllfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	h, _ := New64(8)
+	h.Add64(fakeHash64(0x10fffffffffff))
+	hpp, _ := NewPlus(10)
+	for i := 0; i < 50; i++ {
+		hpp.Add(fakeHash64(uint64(i) * 0x9e3779b97f4a7c15))
+	}
+
+	if _, err := db.Exec("put", "hll", h); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("put", "hllpp", hpp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("put", "null", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var h2 HyperLogLog
+	if err := db.QueryRow("get", "hll").Scan(&h2); err != nil {
+		t.Fatal(err)
+	}
+	if !h.Equal(&h2) {
+		t.Error("scanned HyperLogLog differs")
+	}
+
+	var hpp2 HyperLogLogPlus
+	if err := db.QueryRow("get", "hllpp").Scan(&hpp2); err != nil {
+		t.Fatal(err)
+	}
+	if !hpp.Equal(&hpp2) || hpp.Count() != hpp2.Count() {
+		t.Error("scanned HyperLogLogPlus differs")
+	}
+
+	if err := db.QueryRow("get", "null").Scan(&hpp2); err == nil {
+		t.Error("scanning NULL should fail")
+	}
+	if err := db.QueryRow("get", "hll").Scan(&hpp2); err == nil {
+		t.Error("scanning HyperLogLog into HyperLogLogPlus should fail")
+	}
+}
+
+func TestScanTypes(t *testing.T) {
+	h, _ := New(4)
+	b, _ := h.MarshalBinary()
+
+	var h2 HyperLogLog
+	if err := h2.Scan(string(b)); err != nil {
+		t.Error(err)
+	}
+	if err := h2.Scan(42); err == nil {
+		t.Error("scanning int should fail")
+	}
+}
@@ -0,0 +1,478 @@
+// Package store implements a durable map from keys to HyperLogLog++ sketches
+// on local disk.
+//
+// Every Add and Merge is appended to a write-ahead log before it returns.
+// Compaction writes all sketches to a snapshot file in the binary format of
+// the hyperloglog package and then empties the log. Open restores the
+// snapshot and replays the log on top of it. Since adding an item or merging
+// a sketch a second time has no effect, replaying operations that are
+// already in the snapshot is harmless, which keeps recovery simple after a
+// crash at any point during compaction.
+//
+// A store directory must only be opened by one Store at a time.
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+const (
+	snapshotName = "snapshot"
+	walName      = "wal"
+)
+
+// Each log record is the length and CRC-32C of its payload, followed by the
+// payload. A payload starts with its operation.
+const (
+	opAdd = iota + 1
+	opMerge
+)
+
+const recordHeaderLen = 8
+
+// The longest record payload replay accepts. Longer lengths can only come from
+// a torn or corrupt record.
+const maxRecordLen = 1 << 28
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errClosed = errors.New("store is closed")
+
+var errChecksum = errors.New("record checksum mismatch")
+
+// Options configure a Store.
+type Options struct {
+	// Precision of the sketches created for new keys. Defaults to 14.
+	Precision uint8
+
+	// CompactEvery is the number of log records after which the store
+	// compacts itself. Defaults to 10000. A negative value disables automatic
+	// compaction.
+	CompactEvery int
+
+	// SyncWrites makes every Add and Merge sync the log to disk before it
+	// returns. Without it, writes survive a crash of the process but not of
+	// the machine.
+	SyncWrites bool
+}
+
+// Store is a durable map from keys to HyperLogLog++ sketches. It is safe for
+// concurrent use.
+type Store struct {
+	mu       sync.RWMutex
+	dir      string
+	opts     Options
+	sketches map[string]*hyperloglog.HyperLogLogPlus
+	wal      *os.File
+	records  int
+	err      error
+}
+
+// Open opens the store in directory dir, creating it if it does not exist,
+// and recovers its sketches from the last snapshot and the log. A record torn
+// by a crash at the end of the log is discarded, but Open fails if any other
+// record cannot be read.
+func Open(dir string, opts *Options) (*Store, error) {
+	s := &Store{
+		dir:      dir,
+		sketches: make(map[string]*hyperloglog.HyperLogLogPlus),
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Precision == 0 {
+		s.opts.Precision = 14
+	}
+	if s.opts.CompactEvery == 0 {
+		s.opts.CompactEvery = 10000
+	}
+	if _, err := hyperloglog.NewPlus(s.opts.Precision); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := s.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.replay(wal); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	s.wal = wal
+	return s, nil
+}
+
+func (s *Store) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(s.dir, snapshotName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := hyperloglog.NewSketchReader(f)
+	for {
+		h := &hyperloglog.HyperLogLogPlus{}
+		key, err := r.Next(h)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.sketches[key] = h
+	}
+}
+
+// Replays the records in wal and leaves it positioned for appending. A crash
+// can only tear the last record, so a record that runs past the end of the log
+// or the last record failing its checksum is truncated. Any other error is
+// returned.
+func (s *Store) replay(wal *os.File) error {
+	fi, err := wal.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(wal)
+	var off int64
+	for {
+		payload, err := readRecord(r, fi.Size()-off)
+		if err == errChecksum {
+			if _, perr := r.Peek(1); perr == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.apply(payload); err != nil {
+			return err
+		}
+		off += int64(recordHeaderLen + len(payload))
+		s.records++
+	}
+
+	if err := wal.Truncate(off); err != nil {
+		return err
+	}
+	_, err = wal.Seek(off, io.SeekStart)
+	return err
+}
+
+// Reads the payload of the next record from r, which has remaining bytes left.
+// It returns io.EOF if there are no more records, io.ErrUnexpectedEOF if the
+// record runs past the end, and errChecksum if its checksum does not match.
+func readRecord(r io.Reader, remaining int64) ([]byte, error) {
+	var hdr [recordHeaderLen]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(hdr[:4])
+	if int64(n) > remaining-recordHeaderLen {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if n > maxRecordLen {
+		return nil, errors.New("record is too long")
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(hdr[4:]) {
+		return nil, errChecksum
+	}
+	return payload, nil
+}
+
+// Applies a record payload during replay.
+func (s *Store) apply(payload []byte) error {
+	op, key, rest, err := decodePayload(payload)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case opAdd:
+		if len(rest) < 1 || (len(rest)-1)%8 != 0 {
+			return errors.New("invalid add record")
+		}
+		h, err := s.sketch(key, rest[0])
+		if err != nil {
+			return err
+		}
+		hashes := make([]uint64, (len(rest)-1)/8)
+		for i := range hashes {
+			hashes[i] = binary.LittleEndian.Uint64(rest[1+8*i:])
+		}
+		h.AddHashes(hashes)
+	case opMerge:
+		other := &hyperloglog.HyperLogLogPlus{}
+		if err := other.UnmarshalBinary(rest); err != nil {
+			return err
+		}
+		if h, ok := s.sketches[key]; ok {
+			return h.Merge(other)
+		}
+		s.sketches[key] = other
+	default:
+		return errors.New("unknown record operation")
+	}
+	return nil
+}
+
+func decodePayload(payload []byte) (uint8, string, []byte, error) {
+	if len(payload) < 1 {
+		return 0, "", nil, errors.New("empty record")
+	}
+	n, k := binary.Uvarint(payload[1:])
+	if k <= 0 || n > uint64(len(payload)-1-k) {
+		return 0, "", nil, errors.New("invalid record key")
+	}
+	key := string(payload[1+k : 1+k+int(n)])
+	return payload[0], key, payload[1+k+int(n):], nil
+}
+
+func encodePayload(op uint8, key string, rest []byte) []byte {
+	b := make([]byte, recordHeaderLen, recordHeaderLen+1+binary.MaxVarintLen64+len(key)+len(rest))
+	b = append(b, op)
+	b = binary.AppendUvarint(b, uint64(len(key)))
+	b = append(b, key...)
+	return append(b, rest...)
+}
+
+// Returns the sketch for key, creating it with precision p if it does not
+// exist.
+func (s *Store) sketch(key string, p uint8) (*hyperloglog.HyperLogLogPlus, error) {
+	if h, ok := s.sketches[key]; ok {
+		return h, nil
+	}
+	h, err := hyperloglog.NewPlus(p)
+	if err != nil {
+		return nil, err
+	}
+	s.sketches[key] = h
+	return h, nil
+}
+
+// Appends a record, whose payload follows the space reserved for its header
+// in b, to the log. An error writing the log is returned by every later write,
+// since the sketches in memory are then ahead of the log.
+func (s *Store) append(b []byte) error {
+	payload := b[recordHeaderLen:]
+	binary.LittleEndian.PutUint32(b[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(b[4:], crc32.Checksum(payload, crcTable))
+
+	if _, err := s.wal.Write(b); err != nil {
+		s.err = err
+		return err
+	}
+	if s.opts.SyncWrites {
+		if err := s.wal.Sync(); err != nil {
+			s.err = err
+			return err
+		}
+	}
+
+	s.records++
+	if s.opts.CompactEvery > 0 && s.records >= s.opts.CompactEvery {
+		return s.compact()
+	}
+	return nil
+}
+
+// Add adds items to the sketch for key, creating it if it does not exist.
+func (s *Store) Add(key string, items ...hyperloglog.Hash64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+
+	h, err := s.sketch(key, s.opts.Precision)
+	if err != nil {
+		return err
+	}
+	hashes := make([]uint64, len(items))
+	rest := make([]byte, 1+8*len(items))
+	rest[0] = s.opts.Precision
+	for i, item := range items {
+		hashes[i] = item.Sum64()
+		binary.LittleEndian.PutUint64(rest[1+8*i:], hashes[i])
+	}
+	h.AddHashes(hashes)
+	return s.append(encodePayload(opAdd, key, rest))
+}
+
+// Merge merges other into the sketch for key, creating it if it does not
+// exist. A new sketch has the precision from the store's Options, so other must
+// have the same precision.
+func (s *Store) Merge(key string, other *hyperloglog.HyperLogLogPlus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+
+	h, ok := s.sketches[key]
+	if !ok {
+		var err error
+		if h, err = hyperloglog.NewPlus(s.opts.Precision); err != nil {
+			return err
+		}
+	}
+	if err := h.Merge(other); err != nil {
+		return err
+	}
+	s.sketches[key] = h
+
+	b, err := other.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return s.append(encodePayload(opMerge, key, b))
+}
+
+// Count returns the cardinality estimate for key, or 0 if key does not exist.
+func (s *Store) Count(key string) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if h, ok := s.sketches[key]; ok {
+		return h.Count()
+	}
+	return 0
+}
+
+// Keys returns the keys in the store in sorted order.
+func (s *Store) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.sketches))
+	for k := range s.sketches {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Compact writes all sketches to a new snapshot and empties the log.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.compact()
+}
+
+func (s *Store) compact() error {
+	if err := s.writeSnapshot(); err != nil {
+		return err
+	}
+
+	// The snapshot now holds everything in the log. If truncating fails the
+	// log is replayed on top of the snapshot, which is harmless.
+	if err := s.wal.Truncate(0); err != nil {
+		s.err = err
+		return err
+	}
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		s.err = err
+		return err
+	}
+	s.records = 0
+	return nil
+}
+
+// Writes the sketches to a temporary file and renames it over the snapshot.
+func (s *Store) writeSnapshot() error {
+	tmp := filepath.Join(s.dir, snapshotName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	keys := make([]string, 0, len(s.sketches))
+	for k := range s.sketches {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w := hyperloglog.NewSketchWriter(f)
+	for _, k := range keys {
+		if err := w.Write(k, s.sketches[k]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, filepath.Join(s.dir, snapshotName)); err != nil {
+		return err
+	}
+	return syncDir(s.dir)
+}
+
+// Syncs directory dir so that a rename in it is durable. Not every platform
+// can sync a directory, so errors from Sync are ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	d.Sync()
+	return d.Close()
+}
+
+// Close compacts the store and closes its log. The store cannot be written to
+// after it is closed.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.wal == nil {
+		return errClosed
+	}
+
+	err := s.err
+	if err == nil {
+		err = s.compact()
+	}
+	if err2 := s.wal.Close(); err == nil {
+		err = err2
+	}
+	s.wal = nil
+	s.err = errClosed
+	return err
+}
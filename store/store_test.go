@@ -0,0 +1,223 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clarkduvall/hyperloglog"
+)
+
+type fakeHash64 uint64
+
+func (f fakeHash64) Sum64() uint64 { return uint64(f) }
+
+func items(start, n int) []hyperloglog.Hash64 {
+	xs := make([]hyperloglog.Hash64, n)
+	for i := range xs {
+		xs[i] = fakeHash64(uint64(start+i) * 0x9e3779b97f4a7c15)
+	}
+	return xs
+}
+
+// Opens the store in dir, failing the test on error.
+func open(t *testing.T, dir string, opts *Options) *Store {
+	t.Helper()
+	s, err := Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Simulates a crash by closing the log without compacting.
+func crash(s *Store) {
+	s.wal.Close()
+}
+
+func fill(t *testing.T, s *Store) map[string]uint64 {
+	t.Helper()
+	for i := 0; i < 10; i++ {
+		if err := s.Add("a", items(100*i, 100)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Add("b", items(5000, 3)...); err != nil {
+		t.Fatal(err)
+	}
+
+	h, _ := hyperloglog.NewPlus(10)
+	for _, x := range items(900, 2000) {
+		h.Add(x)
+	}
+	if err := s.Merge("a", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Merge("c", h); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]uint64{"a": s.Count("a"), "b": s.Count("b"), "c": s.Count("c")}
+}
+
+func check(t *testing.T, s *Store, counts map[string]uint64) {
+	t.Helper()
+	if keys := s.Keys(); len(keys) != len(counts) {
+		t.Error(keys)
+	}
+	for k, n := range counts {
+		if c := s.Count(k); c != n {
+			t.Error(k, c, n)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, &Options{Precision: 10})
+	counts := fill(t, s)
+	if counts["b"] != 3 || counts["c"] == 0 || counts["a"] < counts["c"] {
+		t.Error(counts)
+	}
+	if s.Count("missing") != 0 {
+		t.Error(s.Count("missing"))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("a"); err != errClosed {
+		t.Error(err)
+	}
+
+	s = open(t, dir, &Options{Precision: 10})
+	defer s.Close()
+	check(t, s, counts)
+	if fi, _ := os.Stat(filepath.Join(dir, walName)); fi.Size() != 0 {
+		t.Error("log should be empty after Close", fi.Size())
+	}
+}
+
+func TestStoreRecoverLog(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, &Options{Precision: 10, SyncWrites: true})
+	counts := fill(t, s)
+	crash(s)
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotName)); !os.IsNotExist(err) {
+		t.Error("snapshot should not exist", err)
+	}
+	s = open(t, dir, &Options{Precision: 10})
+	defer s.Close()
+	check(t, s, counts)
+}
+
+func TestStoreTornLog(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, &Options{Precision: 10})
+	counts := fill(t, s)
+	crash(s)
+
+	path := filepath.Join(dir, walName)
+	fi, _ := os.Stat(path)
+	size := fi.Size()
+	for _, tail := range [][]byte{
+		{1, 2, 3},
+		{20, 0, 0, 0, 1, 2, 3, 4, 1, 1, 'x'},
+		{255, 255, 255, 255, 0, 0, 0, 0},
+		{3, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3},
+	} {
+		f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		f.Write(tail)
+		f.Close()
+
+		s = open(t, dir, &Options{Precision: 10})
+		check(t, s, counts)
+		crash(s)
+		if fi, _ := os.Stat(path); fi.Size() != size {
+			t.Error("torn record not truncated", fi.Size(), size)
+		}
+	}
+
+	s = open(t, dir, &Options{Precision: 10})
+	defer s.Close()
+	if err := s.Add("d", items(0, 1)...); err != nil {
+		t.Fatal(err)
+	}
+	counts["d"] = 1
+	check(t, s, counts)
+}
+
+func TestStoreCorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, &Options{Precision: 10})
+	fill(t, s)
+	crash(s)
+
+	// A bad checksum before the last record is corruption, not a torn write.
+	path := filepath.Join(dir, walName)
+	b, _ := os.ReadFile(path)
+	b[recordHeaderLen] ^= 0xff
+	os.WriteFile(path, b, 0644)
+	if _, err := Open(dir, &Options{Precision: 10}); err != errChecksum {
+		t.Error(err)
+	}
+	if fi, _ := os.Stat(path); fi.Size() != int64(len(b)) {
+		t.Error("corrupt log truncated", fi.Size(), len(b))
+	}
+}
+
+func TestStoreCompact(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, &Options{Precision: 10, CompactEvery: 4})
+	counts := fill(t, s)
+	if s.records >= 4 {
+		t.Error(s.records)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotName)); err != nil {
+		t.Error(err)
+	}
+	crash(s)
+
+	s = open(t, dir, &Options{Precision: 10, CompactEvery: -1})
+	check(t, s, counts)
+	s.Add("a", items(0, 1000)...)
+	counts["a"] = s.Count("a")
+
+	// A crash after the snapshot is written but before the log is emptied
+	// replays the log on top of the snapshot.
+	if err := s.writeSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+	crash(s)
+
+	s = open(t, dir, &Options{Precision: 10})
+	defer s.Close()
+	check(t, s, counts)
+}
+
+func TestStoreError(t *testing.T) {
+	if _, err := Open(t.TempDir(), &Options{Precision: 30}); err == nil {
+		t.Error("expected precision error")
+	}
+
+	dir := t.TempDir()
+	s := open(t, dir, nil)
+	h, _ := hyperloglog.NewPlus(10)
+	if err := s.Merge("a", h); err == nil {
+		t.Error("expected precision error")
+	}
+	if len(s.Keys()) != 0 {
+		t.Error("failed Merge created key")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != errClosed {
+		t.Error(err)
+	}
+
+	os.WriteFile(filepath.Join(dir, snapshotName), []byte("junk"), 0644)
+	if _, err := Open(dir, nil); err == nil {
+		t.Error("expected snapshot error")
+	}
+}
@@ -0,0 +1,118 @@
+package hyperloglog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// A sketch stream starts with streamMagic and a version byte, followed by a
+// frame per sketch. Each frame is the uvarint length of the key, the key, and
+// the sketch as written by WriteTo. Since sketches are self-delimiting, frames
+// do not need to store their length.
+const streamMagic = "HLLS"
+const streamVersion = 1
+
+// The longest key a SketchReader accepts, which bounds the memory used to read
+// a corrupt stream.
+const maxKeyLen = 1 << 16
+
+// SketchWriter writes a stream of keyed sketches to an underlying writer. Only
+// one sketch is held in memory at a time.
+type SketchWriter struct {
+	w           *bufio.Writer
+	wroteHeader bool
+	err         error
+}
+
+// NewSketchWriter returns a SketchWriter writing to w. Flush must be called
+// once all sketches have been written.
+func NewSketchWriter(w io.Writer) *SketchWriter {
+	return &SketchWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes sketch s with key to the stream. After an error all later
+// calls return the same error.
+func (sw *SketchWriter) Write(key string, s io.WriterTo) error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if len(key) > maxKeyLen {
+		return errors.New("key is too long")
+	}
+
+	sw.writeHeader()
+
+	var buf [binary.MaxVarintLen64]byte
+	sw.w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(key)))])
+	sw.w.WriteString(key)
+	_, sw.err = s.WriteTo(sw.w)
+	return sw.err
+}
+
+// Flush writes any buffered data to the underlying writer. A stream with no
+// sketches is written as just its header.
+func (sw *SketchWriter) Flush() error {
+	if sw.err != nil {
+		return sw.err
+	}
+	sw.writeHeader()
+	sw.err = sw.w.Flush()
+	return sw.err
+}
+
+func (sw *SketchWriter) writeHeader() {
+	if !sw.wroteHeader {
+		sw.w.WriteString(streamMagic)
+		sw.w.WriteByte(streamVersion)
+		sw.wroteHeader = true
+	}
+}
+
+// SketchReader reads a stream of keyed sketches written by a SketchWriter.
+type SketchReader struct {
+	r          *bufio.Reader
+	readHeader bool
+}
+
+// NewSketchReader returns a SketchReader reading from r. It may read ahead of
+// the sketches it has returned.
+func NewSketchReader(r io.Reader) *SketchReader {
+	return &SketchReader{r: bufio.NewReader(r)}
+}
+
+// Next reads the next sketch in the stream into s, which must be the same type
+// the sketch was written as, and returns its key. It returns io.EOF once the
+// stream has no more sketches.
+func (sr *SketchReader) Next(s io.ReaderFrom) (string, error) {
+	if !sr.readHeader {
+		var hdr [len(streamMagic) + 1]byte
+		if _, err := io.ReadFull(sr.r, hdr[:]); err != nil {
+			return "", unexpectedEOF(err)
+		}
+		if string(hdr[:len(streamMagic)]) != streamMagic {
+			return "", errors.New("not a sketch stream")
+		}
+		if hdr[len(streamMagic)] != streamVersion {
+			return "", errors.New("unsupported sketch stream version")
+		}
+		sr.readHeader = true
+	}
+
+	n, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		return "", err
+	}
+	if n > maxKeyLen {
+		return "", errors.New("key is too long")
+	}
+	key := make([]byte, n)
+	if _, err := io.ReadFull(sr.r, key); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	if _, err := s.ReadFrom(sr.r); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	return string(key), nil
+}
@@ -0,0 +1,105 @@
+package hyperloglog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestSketchStream(t *testing.T) {
+	var sketches []*HyperLogLogPlus
+	for i := 0; i < 20; i++ {
+		s, _ := NewPlus(10)
+		for j := 0; j < 100*i; j++ {
+			s.Add(fakeHash64(uint64(i*10000+j) * 0x9e3779b97f4a7c15))
+		}
+		sketches = append(sketches, s)
+	}
+
+	var buf bytes.Buffer
+	w := NewSketchWriter(&buf)
+	for i, s := range sketches {
+		if err := w.Write(fmt.Sprint("key", i), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewSketchReader(&buf)
+	for i, s := range sketches {
+		var s2 HyperLogLogPlus
+		key, err := r.Next(&s2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != fmt.Sprint("key", i) {
+			t.Error(key)
+		}
+		if !s.Equal(&s2) {
+			t.Error(i, "sketch differs")
+		}
+	}
+	if _, err := r.Next(&HyperLogLogPlus{}); err != io.EOF {
+		t.Error(err)
+	}
+}
+
+func TestSketchStreamEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewSketchWriter(&buf).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "HLLS\x01" {
+		t.Errorf("%q", buf.String())
+	}
+	if _, err := NewSketchReader(&buf).Next(&HyperLogLog{}); err != io.EOF {
+		t.Error(err)
+	}
+}
+
+func TestSketchStreamError(t *testing.T) {
+	h, _ := New(4)
+	var buf bytes.Buffer
+	w := NewSketchWriter(&buf)
+	w.Write("a", h)
+	w.Flush()
+	b := buf.Bytes()
+
+	for _, b := range [][]byte{
+		nil,
+		[]byte("HLL"),
+		[]byte("HLLX\x01"),
+		[]byte("HLLS\x02"),
+		b[:len(b)-1],
+		b[:6],
+		[]byte("HLLS\x01\xff\xff\x04"),
+	} {
+		if _, err := NewSketchReader(bytes.NewReader(b)).Next(&HyperLogLog{}); err == nil || err == io.EOF {
+			t.Errorf("%q: %v", b, err)
+		}
+	}
+
+	if _, err := NewSketchReader(bytes.NewReader(b)).Next(&HyperLogLogPlus{}); err == nil {
+		t.Error("read HyperLogLog as HyperLogLogPlus")
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("fail") }
+
+func TestSketchWriterError(t *testing.T) {
+	h, _ := NewPlus(16)
+	h.toNormal()
+	w := NewSketchWriter(failWriter{})
+	if err := w.Write("a", h); err == nil {
+		t.Error("expected error")
+	}
+	if err := w.Flush(); err == nil {
+		t.Error("expected error")
+	}
+}